@@ -0,0 +1,150 @@
+package nvector
+
+import (
+	"math"
+	"sort"
+)
+
+// BoundingBox returns the extent in longitude and latitude (in radians) of a
+// set of positions. Where the positions straddle the antimeridian, the smaller
+// of the two possible longitude spans is chosen, and it is returned with
+// *minLon* greater than *maxLon* to show that the box wraps through ±180
+// degrees. Positions at a pole have no meaningful longitude and only extend
+// the latitude range, so a set of points all at a pole gives a box spanning
+// every longitude. Note that this is the box of the points themselves: points
+// surrounding a pole give a box that stops short of it. All four values are
+// NaN if *pts* is empty.
+func BoundingBox(pts []LonLat) (minLon, minLat, maxLon, maxLat float64) {
+	if len(pts) == 0 {
+		nan := math.NaN()
+		return nan, nan, nan, nan
+	}
+
+	minLat, maxLat = math.Inf(1), math.Inf(-1)
+	lons := make([]float64, 0, len(pts))
+	for _, ll := range pts {
+		minLat = math.Min(minLat, ll.Lat)
+		maxLat = math.Max(maxLat, ll.Lat)
+		if math.Abs(ll.Lat) < 0.5*math.Pi {
+			lons = append(lons, wrapAngle(ll.Lon))
+		}
+	}
+	if len(lons) == 0 {
+		return -math.Pi, minLat, math.Pi, maxLat
+	}
+	sort.Float64s(lons)
+
+	// the box excludes the widest empty gap between consecutive longitudes,
+	// starting with the gap across the antimeridian
+	n := len(lons)
+	minLon, maxLon = lons[0], lons[n-1]
+	widest := lons[0] + 2*math.Pi - lons[n-1]
+	for i := 1; i < n; i++ {
+		if gap := lons[i] - lons[i-1]; gap > widest {
+			widest = gap
+			minLon, maxLon = lons[i], lons[i-1]
+		}
+	}
+	return minLon, minLat, maxLon, maxLat
+}
+
+// clipInterval returns the range of the parameter t in [0, 1] for which
+// x0 + t*dx lies within [lo, hi], and false if there is none. It is one step
+// of Liang-Barsky clipping.
+func clipInterval(x0, dx, lo, hi float64, t0, t1 float64) (float64, float64, bool) {
+	if dx == 0 {
+		return t0, t1, x0 >= lo && x0 <= hi
+	}
+	ta, tb := (lo-x0)/dx, (hi-x0)/dx
+	if ta > tb {
+		ta, tb = tb, ta
+	}
+	t0, t1 = math.Max(t0, ta), math.Min(t1, tb)
+	return t0, t1, t0 <= t1
+}
+
+// ClipToBox returns the parts of *line* that lie within the box bounded by
+// the longitudes *minLon* and *maxLon* and the latitudes *minLat* and
+// *maxLat*, in radians, with points inserted where the line crosses the edge
+// of the box. As with BoundingBox, a *minLon* greater than *maxLon* describes
+// a box that wraps through the antimeridian. Segments are treated as straight
+// in longitude and latitude, taking the shorter way around in longitude, as
+// map tiles are, rather than as great circles. A line of a single point
+// inside the box is returned as it is.
+func ClipToBox(line []LonLat, minLon, minLat, maxLon, maxLat float64) [][]LonLat {
+	width := maxLon - minLon
+	if width < 0 {
+		width += 2 * math.Pi
+	}
+	// longitudes are measured eastward from *minLon*, so that the box
+	// occupies [0, width] and its copies a full turn to either side
+	fromMin := func(lon float64) float64 {
+		return math.Mod(wrapAngle(lon-minLon)+2*math.Pi, 2*math.Pi)
+	}
+
+	if len(line) == 1 {
+		if fromMin(line[0].Lon) <= width && line[0].Lat >= minLat && line[0].Lat <= maxLat {
+			return [][]LonLat{{line[0]}}
+		}
+		return nil
+	}
+
+	var parts [][]LonLat
+	var current []LonLat
+	flush := func() {
+		if len(current) >= 2 {
+			parts = append(parts, current)
+		}
+		current = nil
+	}
+
+	type piece struct{ t0, t1 float64 }
+	continuing := false
+	for i := 1; i < len(line); i++ {
+		a, b := line[i-1], line[i]
+		x0 := fromMin(a.Lon)
+		dx := wrapAngle(b.Lon - a.Lon)
+		dy := b.Lat - a.Lat
+
+		var pieces []piece
+		for k := -1; k <= 1; k++ {
+			offset := 2 * math.Pi * float64(k)
+			t0, t1, ok := clipInterval(x0, dx, offset, offset+width, 0, 1)
+			if ok {
+				t0, t1, ok = clipInterval(a.Lat, dy, minLat, maxLat, t0, t1)
+			}
+			if ok && t1 > t0 {
+				pieces = append(pieces, piece{t0, t1})
+			}
+		}
+		sort.Slice(pieces, func(i, j int) bool { return pieces[i].t0 < pieces[j].t0 })
+
+		at := func(t float64) LonLat {
+			switch t {
+			case 0:
+				return a
+			case 1:
+				return b
+			}
+			return LonLat{WrapLongitude(a.Lon + t*dx), a.Lat + t*dy}
+		}
+
+		prevEnd := -1.0
+		for _, p := range pieces {
+			if (p.t0 == 0 && continuing) || p.t0 == prevEnd {
+				current = append(current, at(p.t1))
+			} else {
+				flush()
+				current = []LonLat{at(p.t0), at(p.t1)}
+			}
+			prevEnd = p.t1
+			continuing = false
+		}
+		continuing = prevEnd == 1
+		if !continuing {
+			flush()
+		}
+	}
+	flush()
+	return parts
+}
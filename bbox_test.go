@@ -0,0 +1,81 @@
+package nvector
+
+import (
+	"math"
+	"testing"
+)
+
+func TestBoundingBox(t *testing.T) {
+	deg := math.Pi / 180
+	pts := []LonLat{{10 * deg, 5 * deg}, {-20 * deg, 30 * deg}, {15 * deg, -12 * deg}}
+	minLon, minLat, maxLon, maxLat := BoundingBox(pts)
+	if !isclose(minLon, -20*deg, 12) || !isclose(maxLon, 15*deg, 12) {
+		t.Fail()
+	}
+	if !isclose(minLat, -12*deg, 12) || !isclose(maxLat, 30*deg, 12) {
+		t.Fail()
+	}
+}
+
+func TestBoundingBoxAntimeridian(t *testing.T) {
+	// points around Fiji
+	deg := math.Pi / 180
+	pts := []LonLat{{177 * deg, -17 * deg}, {-179 * deg, -16 * deg}, {178.5 * deg, -19 * deg}}
+	minLon, minLat, maxLon, maxLat := BoundingBox(pts)
+	if !isclose(minLon, 177*deg, 12) || !isclose(maxLon, -179*deg, 12) {
+		t.Errorf("longitude span %f to %f", minLon/deg, maxLon/deg)
+	}
+	if !isclose(minLat, -19*deg, 12) || !isclose(maxLat, -16*deg, 12) {
+		t.Fail()
+	}
+}
+
+func TestBoundingBoxPole(t *testing.T) {
+	deg := math.Pi / 180
+	pts := []LonLat{{0, 90 * deg}, {45 * deg, 90 * deg}}
+	minLon, minLat, maxLon, maxLat := BoundingBox(pts)
+	if minLon != -math.Pi || maxLon != math.Pi || minLat != 0.5*math.Pi || maxLat != 0.5*math.Pi {
+		t.Fail()
+	}
+
+	minLon, _, _, _ = BoundingBox(nil)
+	if !math.IsNaN(minLon) {
+		t.Fail()
+	}
+}
+
+func TestClipToBox(t *testing.T) {
+	deg := math.Pi / 180
+	// a line entering the box, leaving it, and entering again
+	line := []LonLat{{-5 * deg, 5 * deg}, {5 * deg, 5 * deg}, {15 * deg, 5 * deg}, {5 * deg, 8 * deg}}
+	parts := ClipToBox(line, 0, 0, 10*deg, 10*deg)
+	if len(parts) != 2 {
+		t.Fatal(parts)
+	}
+	if len(parts[0]) != 3 || !isclose(parts[0][0].Lon, 0, 12) || parts[0][1] != line[1] ||
+		!isclose(parts[0][2].Lon, 10*deg, 12) {
+		t.Error(parts[0])
+	}
+	if len(parts[1]) != 2 || !isclose(parts[1][0].Lon, 10*deg, 12) ||
+		!isclose(parts[1][0].Lat, 6.5*deg, 12) || parts[1][1] != line[3] {
+		t.Error(parts[1])
+	}
+
+	// wholly outside
+	if parts := ClipToBox(line, 20*deg, 0, 30*deg, 10*deg); len(parts) != 0 {
+		t.Error(parts)
+	}
+}
+
+func TestClipToBoxAntimeridian(t *testing.T) {
+	deg := math.Pi / 180
+	line := []LonLat{{170 * deg, -18 * deg}, {179 * deg, -18 * deg}, {-175 * deg, -18 * deg}, {-160 * deg, -18 * deg}}
+	parts := ClipToBox(line, 177*deg, -20*deg, -178*deg, -15*deg)
+	if len(parts) != 1 || len(parts[0]) != 3 {
+		t.Fatal(parts)
+	}
+	p := parts[0]
+	if !isclose(p[0].Lon, 177*deg, 12) || p[1] != line[1] || !isclose(p[2].Lon, -178*deg, 12) {
+		t.Error(p)
+	}
+}
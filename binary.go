@@ -0,0 +1,67 @@
+package nvector
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// BinaryLengthError is returned when binary data has neither of the lengths
+// written by MarshalBinary and MarshalBinaryPrecise
+type BinaryLengthError struct {
+	Length int
+}
+
+func (e BinaryLengthError) Error() string {
+	return fmt.Sprintf("binary position must be 8 or 16 bytes, not %d", e.Length)
+}
+
+// MarshalBinary encodes the position in 8 bytes, as its longitude and
+// latitude in degrees stored as little-endian float32s. This is a third of
+// the size of the n-vector itself, but the 24 bits of precision in a float32
+// limit the resolution to about a metre at longitudes near ±180⁰. Use
+// MarshalBinaryPrecise where that isn't enough.
+func (nv *NVector) MarshalBinary() ([]byte, error) {
+	ll := nv.ToLonLat()
+	lon, lat := ll.Degrees()
+	buf := make([]byte, 8)
+	binary.LittleEndian.PutUint32(buf[0:], math.Float32bits(float32(lon)))
+	binary.LittleEndian.PutUint32(buf[4:], math.Float32bits(float32(lat)))
+	return buf, nil
+}
+
+// MarshalBinaryPrecise encodes the position in 16 bytes, as its longitude and
+// latitude in degrees stored as little-endian float64s, preserving the
+// position to within rounding
+func (nv *NVector) MarshalBinaryPrecise() ([]byte, error) {
+	ll := nv.ToLonLat()
+	lon, lat := ll.Degrees()
+	buf := make([]byte, 16)
+	binary.LittleEndian.PutUint64(buf[0:], math.Float64bits(lon))
+	binary.LittleEndian.PutUint64(buf[8:], math.Float64bits(lat))
+	return buf, nil
+}
+
+// UnmarshalBinary sets the position from data written by either MarshalBinary
+// or MarshalBinaryPrecise, telling them apart by length. BinaryLengthError is
+// returned for data of any other length, and InvalidLatitudeError if the
+// latitude is out of range.
+func (nv *NVector) UnmarshalBinary(data []byte) error {
+	var lon, lat float64
+	switch len(data) {
+	case 8:
+		lon = float64(math.Float32frombits(binary.LittleEndian.Uint32(data[0:])))
+		lat = float64(math.Float32frombits(binary.LittleEndian.Uint32(data[4:])))
+	case 16:
+		lon = math.Float64frombits(binary.LittleEndian.Uint64(data[0:]))
+		lat = math.Float64frombits(binary.LittleEndian.Uint64(data[8:]))
+	default:
+		return BinaryLengthError{len(data)}
+	}
+	ll, err := NewLonLat(lon, lat)
+	if err != nil {
+		return err
+	}
+	*nv = ll.ToNVector()
+	return nil
+}
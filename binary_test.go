@@ -0,0 +1,72 @@
+package nvector
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/binary"
+	"encoding/gob"
+	"math"
+	"testing"
+)
+
+var _ encoding.BinaryMarshaler = &NVector{}
+var _ encoding.BinaryUnmarshaler = &NVector{}
+
+func TestMarshalBinary(t *testing.T) {
+	R := 6371e3
+	for _, c := range [][2]float64{{0, 0}, {-123.1, 49.3}, {179.99, -89.5}, {-179.99, 1e-3}} {
+		ll, _ := NewLonLat(c[0], c[1])
+		nv := ll.ToNVector()
+
+		data, err := nv.MarshalBinary()
+		if err != nil || len(data) != 8 {
+			t.Fatal(err, len(data))
+		}
+		var back NVector
+		if err := back.UnmarshalBinary(data); err != nil {
+			t.Fatal(err)
+		}
+		if d := nv.SphericalDistance(&back, R); d > 1.5 {
+			t.Error(c, d)
+		}
+
+		data, _ = nv.MarshalBinaryPrecise()
+		if len(data) != 16 {
+			t.Fatal(len(data))
+		}
+		if err := back.UnmarshalBinary(data); err != nil {
+			t.Fatal(err)
+		}
+		if d := nv.SphericalDistance(&back, R); d > 1e-6 {
+			t.Error(c, d)
+		}
+	}
+}
+
+func TestUnmarshalBinaryInvalid(t *testing.T) {
+	var nv NVector
+	if _, ok := nv.UnmarshalBinary(make([]byte, 12)).(BinaryLengthError); !ok {
+		t.Fail()
+	}
+	data := make([]byte, 8)
+	binary.LittleEndian.PutUint32(data[4:], math.Float32bits(95))
+	if _, ok := nv.UnmarshalBinary(data).(InvalidLatitudeError); !ok {
+		t.Fail()
+	}
+}
+
+func TestMarshalBinaryGob(t *testing.T) {
+	ll, _ := NewLonLat(-70.5, -33.4)
+	nv := ll.ToNVector()
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&nv); err != nil {
+		t.Fatal(err)
+	}
+	var back NVector
+	if err := gob.NewDecoder(&buf).Decode(&back); err != nil {
+		t.Fatal(err)
+	}
+	if d := nv.SphericalDistance(&back, 6371e3); d > 1.5 {
+		t.Error(d)
+	}
+}
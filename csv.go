@@ -0,0 +1,91 @@
+package nvector
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// csvColumn returns the axis named by a CSV header field, if any
+func csvColumn(name string) axis {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "lon", "lng", "long", "longitude", "x":
+		return axisLon
+	case "lat", "latitude", "y":
+		return axisLat
+	}
+	return axisUnknown
+}
+
+// ReadLonLatCSV reads positions in decimal degrees from CSV records. If the
+// first record is a header naming longitude and latitude columns (e.g. "lon"
+// and "lat", in either order, among other columns) the values are taken from
+// those columns. Otherwise there is no header, and the first two columns are
+// taken as longitude and latitude. ParseError is returned for records that
+// can't be read, and InvalidLatitudeError for latitudes outside [-90, 90].
+func ReadLonLatCSV(r io.Reader) ([]LonLat, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return []LonLat{}, nil
+	}
+
+	lonCol, latCol := 0, 1
+	lonFound, latFound := false, false
+	for i, field := range records[0] {
+		switch csvColumn(field) {
+		case axisLon:
+			lonCol, lonFound = i, true
+		case axisLat:
+			latCol, latFound = i, true
+		}
+	}
+	if lonFound && latFound {
+		records = records[1:]
+	}
+
+	pts := make([]LonLat, 0, len(records))
+	for _, record := range records {
+		if len(record) <= lonCol || len(record) <= latCol {
+			return pts, ParseError{strings.Join(record, ",")}
+		}
+		lon, errLon := strconv.ParseFloat(strings.TrimSpace(record[lonCol]), 64)
+		lat, errLat := strconv.ParseFloat(strings.TrimSpace(record[latCol]), 64)
+		if errLon != nil || errLat != nil {
+			return pts, ParseError{strings.Join(record, ",")}
+		}
+		ll, err := NewLonLat(lon, lat)
+		if err != nil {
+			return pts, err
+		}
+		pts = append(pts, *ll)
+	}
+	return pts, nil
+}
+
+// WriteLonLatCSV writes positions as CSV records of longitude and latitude in
+// decimal degrees, following a "lon,lat" header
+func WriteLonLatCSV(w io.Writer, pts []LonLat) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"lon", "lat"}); err != nil {
+		return err
+	}
+	for _, ll := range pts {
+		londeg, latdeg := ll.Degrees()
+		record := []string{
+			strconv.FormatFloat(londeg, 'f', -1, 64),
+			strconv.FormatFloat(latdeg, 'f', -1, 64),
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
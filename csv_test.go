@@ -0,0 +1,62 @@
+package nvector
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestReadLonLatCSV(t *testing.T) {
+	pts, err := ReadLonLatCSV(strings.NewReader("name,Latitude,Longitude\na,49.25,-140\nb,-33.9,151.2\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pts) != 2 {
+		t.Fatal(len(pts))
+	}
+	londeg, latdeg := pts[1].Degrees()
+	if !isclose(londeg, 151.2, 12) || !isclose(latdeg, -33.9, 12) {
+		t.Fail()
+	}
+
+	// without a header the first two columns are lon, lat
+	pts, err = ReadLonLatCSV(strings.NewReader("-140, 49.25\n10,20\n"))
+	if err != nil || len(pts) != 2 {
+		t.Fatal(err)
+	}
+	londeg, latdeg = pts[0].Degrees()
+	if !isclose(londeg, -140, 12) || !isclose(latdeg, 49.25, 12) {
+		t.Fail()
+	}
+}
+
+func TestReadLonLatCSVInvalid(t *testing.T) {
+	if _, err := ReadLonLatCSV(strings.NewReader("lon,lat\n10,95\n")); err == nil {
+		t.Error("expected error for invalid latitude")
+	} else if _, ok := err.(InvalidLatitudeError); !ok {
+		t.Error(err)
+	}
+	if _, err := ReadLonLatCSV(strings.NewReader("lon,lat\n10,north\n")); err == nil {
+		t.Fail()
+	}
+}
+
+func TestWriteLonLatCSV(t *testing.T) {
+	a, _ := NewLonLat(-140, 49.25)
+	b, _ := NewLonLat(151.2, -33.9)
+	var buf bytes.Buffer
+	if err := WriteLonLatCSV(&buf, []LonLat{*a, *b}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(buf.String(), "lon,lat\n") {
+		t.Error(buf.String())
+	}
+
+	pts, err := ReadLonLatCSV(&buf)
+	if err != nil || len(pts) != 2 {
+		t.Fatal(err)
+	}
+	if !isclose(pts[0].Lon, a.Lon, 12) || !isclose(pts[1].Lat, b.Lat, 12) {
+		t.Fail()
+	}
+}
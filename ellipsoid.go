@@ -0,0 +1,219 @@
+package nvector
+
+import (
+	"fmt"
+	"math"
+)
+
+// ConvergenceError is returned when an iterative geodesic calculation fails
+// to converge, as Vincenty's method may for nearly antipodal positions
+type ConvergenceError struct {
+	Iterations int
+}
+
+func (e ConvergenceError) Error() string {
+	return fmt.Sprintf("failed to converge after %d iterations", e.Iterations)
+}
+
+// flattening returns the flattening of the ellipsoid
+func (ellps *Ellipsoid) flattening() float64 {
+	return (ellps.a - ellps.b) / ellps.a
+}
+
+// meanRadius returns the arithmetic mean of the ellipsoid's three semi-axes
+func (ellps *Ellipsoid) meanRadius() float64 {
+	return (2*ellps.a + ellps.b) / 3
+}
+
+// MeridianRadius returns the radius of curvature of the ellipsoid in the
+// meridian, M, at geodetic latitude *lat* in radians
+func (ellps *Ellipsoid) MeridianRadius(lat float64) float64 {
+	e2 := 1 - ellps.b*ellps.b/(ellps.a*ellps.a)
+	w := 1 - e2*math.Sin(lat)*math.Sin(lat)
+	return ellps.a * (1 - e2) / (w * math.Sqrt(w))
+}
+
+// PrimeVerticalRadius returns the radius of curvature of the ellipsoid in the
+// prime vertical, N, at geodetic latitude *lat* in radians. This is the
+// distance along the normal from the surface to the polar axis.
+func (ellps *Ellipsoid) PrimeVerticalRadius(lat float64) float64 {
+	e2 := 1 - ellps.b*ellps.b/(ellps.a*ellps.a)
+	return ellps.a / math.Sqrt(1-e2*math.Sin(lat)*math.Sin(lat))
+}
+
+// GaussianRadius returns the Gaussian mean radius of curvature, sqrt(MN), at
+// geodetic latitude *lat* in radians, which is the radius of the sphere that
+// best fits the ellipsoid locally
+func (ellps *Ellipsoid) GaussianRadius(lat float64) float64 {
+	return math.Sqrt(ellps.MeridianRadius(lat) * ellps.PrimeVerticalRadius(lat))
+}
+
+// SubSatellitePoint returns the geographic position of the point on the
+// ellipsoid directly beneath the Earth-centred, Earth-fixed position *ecef*,
+// along the ellipsoid normal. Its latitude is therefore the geodetic latitude
+// of *ecef*, not the geocentric one.
+func SubSatellitePoint(ecef Vec3, ellps *Ellipsoid) LonLat {
+	pv := PVector{ecef}
+	nv := pv.ToNVector(ellps)
+	return nv.ToLonLat()
+}
+
+// geodeticLonLat returns the longitude and geodetic latitude of *nv*
+func geodeticLonLat(nv *NVector) (float64, float64) {
+	lon := math.Atan2(nv.Vec3[1], nv.Vec3[0])
+	lat := math.Atan2(nv.Vec3[2], math.Sqrt(nv.Vec3[0]*nv.Vec3[0]+nv.Vec3[1]*nv.Vec3[1]))
+	return lon, lat
+}
+
+// vincentyInverse returns the geodesic distance between two positions on an
+// ellipsoid, along with the forward azimuths of the geodesic at each end,
+// using Vincenty's inverse formula.
+func vincentyInverse(nv1, nv2 *NVector, ellps *Ellipsoid) (float64, float64, float64, error) {
+	const maxIterations = 200
+	a, b := ellps.a, ellps.b
+	f := ellps.flattening()
+
+	lon1, lat1 := geodeticLonLat(nv1)
+	lon2, lat2 := geodeticLonLat(nv2)
+	L := lon2 - lon1
+	U1 := math.Atan((1 - f) * math.Tan(lat1))
+	U2 := math.Atan((1 - f) * math.Tan(lat2))
+	sinU1, cosU1 := math.Sincos(U1)
+	sinU2, cosU2 := math.Sincos(U2)
+
+	var sinSigma, cosSigma, sigma, cos2Alpha, cos2SigmaM float64
+	var sinLambda, cosLambda float64
+	lambda := L
+	converged := false
+	for i := 0; i < maxIterations; i++ {
+		sinLambda, cosLambda = math.Sincos(lambda)
+		sinSigma = math.Hypot(cosU2*sinLambda, cosU1*sinU2-sinU1*cosU2*cosLambda)
+		if sinSigma == 0 {
+			return 0, 0, 0, nil
+		}
+		cosSigma = sinU1*sinU2 + cosU1*cosU2*cosLambda
+		sigma = math.Atan2(sinSigma, cosSigma)
+		sinAlpha := cosU1 * cosU2 * sinLambda / sinSigma
+		cos2Alpha = 1 - sinAlpha*sinAlpha
+		cos2SigmaM = 0
+		if cos2Alpha != 0 {
+			cos2SigmaM = cosSigma - 2*sinU1*sinU2/cos2Alpha
+		}
+		C := f / 16 * cos2Alpha * (4 + f*(4-3*cos2Alpha))
+		prev := lambda
+		lambda = L + (1-C)*f*sinAlpha*(sigma+C*sinSigma*(cos2SigmaM+C*cosSigma*(-1+2*cos2SigmaM*cos2SigmaM)))
+		if math.Abs(lambda-prev) < 1e-12 {
+			converged = true
+			break
+		}
+	}
+	if !converged {
+		return math.NaN(), math.NaN(), math.NaN(), ConvergenceError{maxIterations}
+	}
+
+	u2 := cos2Alpha * (a*a - b*b) / (b * b)
+	A := 1 + u2/16384*(4096+u2*(-768+u2*(320-175*u2)))
+	B := u2 / 1024 * (256 + u2*(-128+u2*(74-47*u2)))
+	deltaSigma := B * sinSigma * (cos2SigmaM + B/4*(cosSigma*(-1+2*cos2SigmaM*cos2SigmaM)-
+		B/6*cos2SigmaM*(-3+4*sinSigma*sinSigma)*(-3+4*cos2SigmaM*cos2SigmaM)))
+
+	dist := b * A * (sigma - deltaSigma)
+	az1 := math.Atan2(cosU2*sinLambda, cosU1*sinU2-sinU1*cosU2*cosLambda)
+	az2 := math.Atan2(cosU1*sinLambda, -sinU1*cosU2+cosU1*sinU2*cosLambda)
+	return dist, az1, az2, nil
+}
+
+// vincentyDirect returns the position reached by following the geodesic on an
+// ellipsoid from *nv* for distance *dist* at initial azimuth *az*, along with
+// the forward azimuth of the geodesic there, using Vincenty's direct formula.
+func vincentyDirect(nv *NVector, az, dist float64, ellps *Ellipsoid) (NVector, float64, error) {
+	const maxIterations = 200
+	a, b := ellps.a, ellps.b
+	f := ellps.flattening()
+
+	lon1, lat1 := geodeticLonLat(nv)
+	sinAz, cosAz := math.Sincos(az)
+	U1 := math.Atan((1 - f) * math.Tan(lat1))
+	sinU1, cosU1 := math.Sincos(U1)
+	sigma1 := math.Atan2(math.Tan(U1), cosAz)
+	sinAlpha := cosU1 * sinAz
+	cos2Alpha := 1 - sinAlpha*sinAlpha
+
+	u2 := cos2Alpha * (a*a - b*b) / (b * b)
+	A := 1 + u2/16384*(4096+u2*(-768+u2*(320-175*u2)))
+	B := u2 / 1024 * (256 + u2*(-128+u2*(74-47*u2)))
+
+	var sinSigma, cosSigma, cos2SigmaM float64
+	sigma := dist / (b * A)
+	converged := false
+	for i := 0; i < maxIterations; i++ {
+		cos2SigmaM = math.Cos(2*sigma1 + sigma)
+		sinSigma, cosSigma = math.Sincos(sigma)
+		deltaSigma := B * sinSigma * (cos2SigmaM + B/4*(cosSigma*(-1+2*cos2SigmaM*cos2SigmaM)-
+			B/6*cos2SigmaM*(-3+4*sinSigma*sinSigma)*(-3+4*cos2SigmaM*cos2SigmaM)))
+		prev := sigma
+		sigma = dist/(b*A) + deltaSigma
+		if math.Abs(sigma-prev) < 1e-12 {
+			converged = true
+			break
+		}
+	}
+	if !converged {
+		return NVector{}, math.NaN(), ConvergenceError{maxIterations}
+	}
+	cos2SigmaM = math.Cos(2*sigma1 + sigma)
+	sinSigma, cosSigma = math.Sincos(sigma)
+
+	x := sinU1*sinSigma - cosU1*cosSigma*cosAz
+	lat2 := math.Atan2(sinU1*cosSigma+cosU1*sinSigma*cosAz, (1-f)*math.Hypot(sinAlpha, x))
+	lambda := math.Atan2(sinSigma*sinAz, cosU1*cosSigma-sinU1*sinSigma*cosAz)
+	C := f / 16 * cos2Alpha * (4 + f*(4-3*cos2Alpha))
+	L := lambda - (1-C)*f*sinAlpha*(sigma+C*sinSigma*(cos2SigmaM+C*cosSigma*(-1+2*cos2SigmaM*cos2SigmaM)))
+
+	ll := LonLat{wrapAngle(lon1 + L), lat2}
+	return ll.ToNVector(), math.Atan2(sinAlpha, -x), nil
+}
+
+// InterpolateGeodesic returns the position a fraction *frac* of the way along
+// the geodesic on the ellipsoid from *nv* to *nv2*, by distance on the
+// ellipsoid rather than on a sphere. The geodesic is found with Vincenty's
+// inverse formula, which returns ConvergenceError for nearly antipodal
+// positions, and the position placed along it with the direct formula.
+func (nv *NVector) InterpolateGeodesic(nv2 *NVector, frac float64, ellps *Ellipsoid) (NVector, error) {
+	dist, az, _, err := vincentyInverse(nv, nv2, ellps)
+	if err != nil {
+		return NVector{}, err
+	}
+	pt, _, err := vincentyDirect(nv, az, frac*dist, ellps)
+	return pt, err
+}
+
+// Geod solves geodesic problems on an ellipsoid, in the manner of the Geod
+// classes of PROJ and GeographicLib. It is the ellipsoidal counterpart of
+// spherical methods such as SphericalDistance and Forward. The azimuths it
+// returns are in radians, clockwise from north.
+type Geod struct {
+	Ellps *Ellipsoid
+}
+
+// Inverse returns the geodesic distance in meters between *a* and *b*, the
+// azimuth of the geodesic at *a* toward *b*, and the back azimuth at *b*
+// toward *a*. ConvergenceError is returned for nearly antipodal positions.
+func (g Geod) Inverse(a, b *NVector) (dist, az12, az21 float64, err error) {
+	dist, az12, az2, err := vincentyInverse(a, b, g.Ellps)
+	if err != nil {
+		return dist, az12, az2, err
+	}
+	return dist, az12, wrapAngle(az2 + math.Pi), nil
+}
+
+// Direct returns the position reached by following the geodesic from *a* at
+// azimuth *az12* for *dist* meters, and the back azimuth there toward *a*.
+// ConvergenceError is returned if the solution fails to converge.
+func (g Geod) Direct(a *NVector, az12, dist float64) (b NVector, az21 float64, err error) {
+	b, az2, err := vincentyDirect(a, az12, dist, g.Ellps)
+	if err != nil {
+		return b, az2, err
+	}
+	return b, wrapAngle(az2 + math.Pi), nil
+}
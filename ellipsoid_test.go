@@ -0,0 +1,191 @@
+package nvector
+
+import (
+	"math"
+	"testing"
+)
+
+func TestVincentyInverse(t *testing.T) {
+	// Flinders Peak to Buninyong, from Vincenty (1975)
+	ll1, _ := NewLonLat(144+25.0/60+29.52440/3600, -(37 + 57.0/60 + 3.72030/3600))
+	ll2, _ := NewLonLat(143+55.0/60+35.38390/3600, -(37 + 39.0/60 + 10.15610/3600))
+	nv1 := ll1.ToNVector()
+	nv2 := ll2.ToNVector()
+	ellps := Ellipsoid{6378137.0, 6356752.314245}
+
+	dist, az1, az2, err := vincentyInverse(&nv1, &nv2, &ellps)
+	if err != nil {
+		t.Error(err)
+	}
+	if !isclose(dist, 54972.271, 3) {
+		t.Error(dist)
+	}
+	if !isclose(az1*180/math.Pi+360, 306+52.0/60+5.37/3600, 5) {
+		t.Error(az1 * 180 / math.Pi)
+	}
+	if !isclose(az2*180/math.Pi+360, 307+10.0/60+25.07/3600, 5) {
+		t.Error(az2 * 180 / math.Pi)
+	}
+}
+
+func TestVincentyInverseCoincident(t *testing.T) {
+	ll, _ := NewLonLat(10, 20)
+	nv := ll.ToNVector()
+	ellps := Ellipsoid{6378137.0, 6356752.314245}
+	dist, _, _, err := vincentyInverse(&nv, &nv, &ellps)
+	if err != nil || dist != 0 {
+		t.Fail()
+	}
+}
+
+func TestVincentyInverseAntipodal(t *testing.T) {
+	ll1, _ := NewLonLat(0, 0.5)
+	ll2, _ := NewLonLat(179.7, -0.5)
+	nv1 := ll1.ToNVector()
+	nv2 := ll2.ToNVector()
+	ellps := Ellipsoid{6378137.0, 6356752.314245}
+	_, _, _, err := vincentyInverse(&nv1, &nv2, &ellps)
+	if _, ok := err.(ConvergenceError); !ok {
+		t.Fail()
+	}
+}
+
+func TestVincentyDirect(t *testing.T) {
+	// Flinders Peak to Buninyong, from Vincenty (1975)
+	ll1, _ := NewLonLat(144+25.0/60+29.52440/3600, -(37 + 57.0/60 + 3.72030/3600))
+	nv1 := ll1.ToNVector()
+	ellps := Ellipsoid{6378137.0, 6356752.314245}
+	az1 := (306 + 52.0/60 + 5.37/3600) * math.Pi / 180
+	nv2, az2, err := vincentyDirect(&nv1, az1, 54972.271, &ellps)
+	if err != nil {
+		t.Error(err)
+	}
+	ll2 := nv2.ToLonLat()
+	if !isclose(ll2.Lon*180/math.Pi, 143+55.0/60+35.38390/3600, 6) ||
+		!isclose(ll2.Lat*180/math.Pi, -(37+39.0/60+10.15610/3600), 6) {
+		t.Error(ll2)
+	}
+	if !isclose(az2*180/math.Pi+360, 307+10.0/60+25.07/3600, 5) {
+		t.Error(az2 * 180 / math.Pi)
+	}
+}
+
+func TestInterpolateGeodesic(t *testing.T) {
+	ellps := Ellipsoid{6378137.0, 6356752.314245}
+	ll1, _ := NewLonLat(-60, 70)
+	ll2, _ := NewLonLat(100, 65)
+	nv1 := ll1.ToNVector()
+	nv2 := ll2.ToNVector()
+	total, _, _, _ := vincentyInverse(&nv1, &nv2, &ellps)
+
+	mid, err := nv1.InterpolateGeodesic(&nv2, 0.5, &ellps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d1, _, _, _ := vincentyInverse(&nv1, &mid, &ellps)
+	d2, _, _, _ := vincentyInverse(&mid, &nv2, &ellps)
+	if math.Abs(d1-d2) > 1e-6 || math.Abs(d1+d2-total) > 1e-6 {
+		t.Error(d1, d2, total)
+	}
+
+	// the ellipsoidal midpoint differs measurably from the spherical one
+	sphmid := nv1.InterpolateMany(&nv2, []float64{0.5})[0]
+	if sphmid.SphericalDistance(&mid, 6371e3) < 100 {
+		t.Fail()
+	}
+
+	end, _ := nv1.InterpolateGeodesic(&nv2, 1, &ellps)
+	if !isclose(end.AngleTo(&nv2), 0, 10) {
+		t.Fail()
+	}
+
+	a, _ := NewLonLat(0, 0.5)
+	b, _ := NewLonLat(179.7, -0.5)
+	nva, nvb := a.ToNVector(), b.ToNVector()
+	if _, err := nva.InterpolateGeodesic(&nvb, 0.5, &ellps); err == nil {
+		t.Fail()
+	}
+}
+
+func TestRadiiOfCurvature(t *testing.T) {
+	a, b := 6378137.0, 6356752.3142
+	wgs84 := Ellipsoid{a, b}
+
+	// at the equator M = b²/a and N = a
+	if !isclose(wgs84.MeridianRadius(0), b*b/a, 6) {
+		t.Fail()
+	}
+	if !isclose(wgs84.PrimeVerticalRadius(0), a, 6) {
+		t.Fail()
+	}
+
+	// at the poles both equal a²/b
+	for _, lat := range []float64{0.5 * math.Pi, -0.5 * math.Pi} {
+		if !isclose(wgs84.MeridianRadius(lat), a*a/b, 6) {
+			t.Fail()
+		}
+		if !isclose(wgs84.PrimeVerticalRadius(lat), a*a/b, 6) {
+			t.Fail()
+		}
+		if !isclose(wgs84.GaussianRadius(lat), a*a/b, 6) {
+			t.Fail()
+		}
+	}
+	if !isclose(wgs84.GaussianRadius(0), b, 6) {
+		t.Fail()
+	}
+
+	// on a sphere all are equal to the radius
+	sphere := Ellipsoid{6371e3, 6371e3}
+	if !isclose(sphere.MeridianRadius(0.7), 6371e3, 6) || !isclose(sphere.PrimeVerticalRadius(0.7), 6371e3, 6) {
+		t.Fail()
+	}
+}
+
+func TestSubSatellitePoint(t *testing.T) {
+	wgs84 := Ellipsoid{6378137.0, 6356752.3142}
+	for _, c := range [][2]float64{{-140, 49.25}, {30, -60}, {179, 0}} {
+		ll, _ := NewLonLat(c[0], c[1])
+		nv := ll.ToNVector()
+		surface := nv.ToPVector(&wgs84)
+
+		// a satellite 700 km above the surface
+		h := 700e3
+		ecef := Vec3{surface.Vec3[0] + h*nv.Vec3[0],
+			surface.Vec3[1] + h*nv.Vec3[1],
+			surface.Vec3[2] + h*nv.Vec3[2]}
+		nadir := SubSatellitePoint(ecef, &wgs84)
+		if !isclose(nadir.Lon, ll.Lon, 10) || !isclose(nadir.Lat, ll.Lat, 10) {
+			t.Error(c, nadir.String())
+		}
+	}
+}
+
+func TestGeod(t *testing.T) {
+	g := Geod{&Ellipsoid{6378137.0, 6356752.314245}}
+	ll1, _ := NewLonLat(144+25.0/60+29.52440/3600, -(37 + 57.0/60 + 3.72030/3600))
+	ll2, _ := NewLonLat(143+55.0/60+35.38390/3600, -(37 + 39.0/60 + 10.15610/3600))
+	nv1 := ll1.ToNVector()
+	nv2 := ll2.ToNVector()
+
+	dist, az12, az21, err := g.Inverse(&nv1, &nv2)
+	if err != nil || !isclose(dist, 54972.271, 3) {
+		t.Error(dist, err)
+	}
+	// the back azimuth is the forward azimuth at the end reversed
+	if !isclose(az21*180/math.Pi, 127+10.0/60+25.07/3600, 5) {
+		t.Error(az21 * 180 / math.Pi)
+	}
+
+	b, back, err := g.Direct(&nv1, az12, dist)
+	if err != nil || !isclose(b.AngleTo(&nv2), 0, 10) || !isclose(back, az21, 8) {
+		t.Error(b, back, err)
+	}
+
+	a, _ := NewLonLat(0, 0.5)
+	c, _ := NewLonLat(179.7, -0.5)
+	nva, nvc := a.ToNVector(), c.ToNVector()
+	if _, _, _, err := g.Inverse(&nva, &nvc); err == nil {
+		t.Fail()
+	}
+}
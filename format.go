@@ -0,0 +1,300 @@
+package nvector
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ParseError is returned when a string cannot be interpreted as a coordinate
+type ParseError struct {
+	Input string
+}
+
+func (e ParseError) Error() string {
+	return fmt.Sprintf("could not parse coordinates: %q", e.Input)
+}
+
+type axis int
+
+const (
+	axisUnknown axis = iota
+	axisLon
+	axisLat
+)
+
+// ParseLonLat interprets a string containing a longitude and a latitude,
+// given either as decimal degrees ("12.5, -45.3") or as degrees, minutes and
+// seconds ("45°30'00\"N 122°15'00\"W", "-122°15' 45°30'"). The two values may
+// be separated by a comma, a semicolon or whitespace.
+//
+// When hemisphere letters (N, S, E, W) are present they determine which value
+// is the latitude, and S and W denote negative values. Otherwise the first
+// value is taken to be the longitude and the second the latitude.
+// InvalidLatitudeError is returned for latitudes outside [-90, 90].
+func ParseLonLat(s string) (*LonLat, error) {
+	parts, ok := splitCoordinates(strings.TrimSpace(s))
+	if !ok {
+		return new(LonLat), ParseError{s}
+	}
+
+	var values [2]float64
+	var axes [2]axis
+	for i, part := range parts {
+		v, ax, ok := parseAngle(part)
+		if !ok {
+			return new(LonLat), ParseError{s}
+		}
+		values[i] = v
+		axes[i] = ax
+	}
+
+	switch {
+	case axes[0] == axisUnknown && axes[1] == axisUnknown:
+		axes = [2]axis{axisLon, axisLat}
+	case axes[0] == axisUnknown:
+		axes[0] = axisLon + axisLat - axes[1]
+	case axes[1] == axisUnknown:
+		axes[1] = axisLon + axisLat - axes[0]
+	}
+	if axes[0] == axes[1] {
+		return new(LonLat), ParseError{s}
+	}
+
+	if axes[0] == axisLat {
+		return NewLonLat(values[1], values[0])
+	}
+	return NewLonLat(values[0], values[1])
+}
+
+func isHemisphere(r rune) bool {
+	switch unicode.ToUpper(r) {
+	case 'N', 'S', 'E', 'W':
+		return true
+	}
+	return false
+}
+
+func isDegreeMark(r rune) bool {
+	return r == '°' || r == 'º' || r == 'd' || r == 'D'
+}
+
+// splitCoordinates divides a coordinate string into its two components
+func splitCoordinates(s string) ([]string, bool) {
+	if strings.ContainsAny(s, ",;") {
+		parts := strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ';' })
+		return parts, len(parts) == 2
+	}
+
+	// split on hemisphere letters, which may lead or trail each component
+	runes := []rune(s)
+	var letters []int
+	for i, r := range runes {
+		if isHemisphere(r) {
+			letters = append(letters, i)
+		}
+	}
+	if len(letters) == 2 {
+		var cut int
+		if strings.TrimSpace(string(runes[:letters[0]])) == "" {
+			cut = letters[1]
+		} else {
+			cut = letters[0] + 1
+		}
+		return []string{string(runes[:cut]), string(runes[cut:])}, true
+	}
+
+	fields := strings.Fields(s)
+	if len(fields) == 2 {
+		return fields, true
+	}
+
+	// group whitespace-separated DMS fields, starting a new component at each
+	// field carrying a degree mark
+	var parts []string
+	for _, f := range fields {
+		if strings.IndexFunc(f, isDegreeMark) != -1 || len(parts) == 0 {
+			parts = append(parts, f)
+		} else {
+			parts[len(parts)-1] += " " + f
+		}
+	}
+	return parts, len(parts) == 2
+}
+
+// parseAngle interprets a single decimal or DMS angle in degrees, returning
+// the axis implied by a hemisphere letter, if any
+func parseAngle(s string) (float64, axis, bool) {
+	s = strings.TrimSpace(s)
+	ax := axisUnknown
+	negate := false
+
+	runes := []rune(s)
+	if len(runes) == 0 {
+		return 0, ax, false
+	}
+	var hemisphere rune
+	if isHemisphere(runes[0]) {
+		hemisphere = unicode.ToUpper(runes[0])
+		runes = runes[1:]
+	} else if isHemisphere(runes[len(runes)-1]) {
+		hemisphere = unicode.ToUpper(runes[len(runes)-1])
+		runes = runes[:len(runes)-1]
+	}
+	switch hemisphere {
+	case 'N':
+		ax = axisLat
+	case 'S':
+		ax, negate = axisLat, true
+	case 'E':
+		ax = axisLon
+	case 'W':
+		ax, negate = axisLon, true
+	}
+
+	body := strings.Map(func(r rune) rune {
+		switch {
+		case isDegreeMark(r), r == '\'', r == '′', r == '"', r == '″':
+			return ' '
+		}
+		return r
+	}, string(runes))
+	fields := strings.Fields(body)
+	if len(fields) == 0 || len(fields) > 3 {
+		return 0, ax, false
+	}
+
+	var parts [3]float64
+	for i, f := range fields {
+		v, err := strconv.ParseFloat(f, 64)
+		if err != nil {
+			return 0, ax, false
+		}
+		if i > 0 && (v < 0 || v >= 60 || strings.HasPrefix(f, "+")) {
+			return 0, ax, false
+		}
+		parts[i] = v
+	}
+
+	signed := strings.HasPrefix(fields[0], "-") || strings.HasPrefix(fields[0], "+")
+	if signed && hemisphere != 0 {
+		return 0, ax, false
+	}
+	if strings.HasPrefix(fields[0], "-") {
+		parts[0] = -parts[0]
+		negate = true
+	}
+
+	value := parts[0] + parts[1]/60.0 + parts[2]/3600.0
+	if negate {
+		value = -value
+	}
+	return value, ax, true
+}
+
+// DMS returns the position formatted as degrees, minutes, and whole seconds,
+// latitude first, e.g. 45°30'00"N 122°15'00"W
+func (ll *LonLat) DMS() string {
+	return ll.DMSWithPrecision(0)
+}
+
+// DMSWithPrecision returns the position formatted as degrees, minutes, and
+// seconds, with seconds rounded to *secDecimals* decimal places. Rounding
+// carries into the minutes and degrees, so that seconds never read 60.
+func (ll *LonLat) DMSWithPrecision(secDecimals int) string {
+	if secDecimals < 0 {
+		secDecimals = 0
+	}
+	lat := formatDMS(ll.Lat*180.0/math.Pi, secDecimals, 'N', 'S')
+	lon := formatDMS(ll.Lon*180.0/math.Pi, secDecimals, 'E', 'W')
+	return lat + " " + lon
+}
+
+func formatDMS(deg float64, secDecimals int, pos, neg rune) string {
+	scale := int64(math.Pow(10, float64(secDecimals)))
+	units := int64(math.Round(math.Abs(deg) * 3600 * float64(scale)))
+
+	hemisphere := pos
+	if deg < 0 && units != 0 {
+		hemisphere = neg
+	}
+
+	d := units / (3600 * scale)
+	units -= d * 3600 * scale
+	m := units / (60 * scale)
+	units -= m * 60 * scale
+	s := float64(units) / float64(scale)
+
+	width := 2
+	if secDecimals > 0 {
+		width += secDecimals + 1
+	}
+	return fmt.Sprintf("%d°%02d'%0*.*f\"%c", d, m, width, secDecimals, s, hemisphere)
+}
+
+// formatDegrees returns an angle in degrees to ten decimal places, finer than
+// a millimetre on the ground, without trailing zeros
+func formatDegrees(deg float64) string {
+	s := strconv.FormatFloat(deg, 'f', 10, 64)
+	s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	if s == "-0" {
+		return "0"
+	}
+	return s
+}
+
+// wktCoordinates returns the longitude and latitude in degrees formatted for
+// Well-Known Text, longitude first
+func wktCoordinates(ll *LonLat) string {
+	londeg, latdeg := ll.Degrees()
+	return formatDegrees(londeg) + " " + formatDegrees(latdeg)
+}
+
+// WKT returns the position as a Well-Known Text point in degrees. Following
+// the OGC convention, longitude comes before latitude, the opposite of the
+// order used by String and DMS.
+func (ll *LonLat) WKT() string {
+	return "POINT (" + wktCoordinates(ll) + ")"
+}
+
+// LineStringWKT returns the path through *pts* as a Well-Known Text line
+// string in degrees, with longitude before latitude. An empty path gives
+// "LINESTRING EMPTY".
+func LineStringWKT(pts []LonLat) string {
+	if len(pts) == 0 {
+		return "LINESTRING EMPTY"
+	}
+	coords := make([]string, len(pts))
+	for i := range pts {
+		coords[i] = wktCoordinates(&pts[i])
+	}
+	return "LINESTRING (" + strings.Join(coords, ", ") + ")"
+}
+
+// ParseWKTPoint interprets a Well-Known Text point in degrees, such as
+// "POINT (-122.4 37.8)", with longitude first. The keyword is case
+// insensitive. ParseError is returned for anything else, and
+// InvalidLatitudeError for latitudes outside [-90, 90].
+func ParseWKTPoint(s string) (LonLat, error) {
+	body := strings.TrimSpace(s)
+	if len(body) < 5 || !strings.EqualFold(body[:5], "POINT") {
+		return LonLat{}, ParseError{s}
+	}
+	body = strings.TrimSpace(body[5:])
+	if !strings.HasPrefix(body, "(") || !strings.HasSuffix(body, ")") {
+		return LonLat{}, ParseError{s}
+	}
+	fields := strings.Fields(body[1 : len(body)-1])
+	if len(fields) != 2 {
+		return LonLat{}, ParseError{s}
+	}
+	lon, errLon := strconv.ParseFloat(fields[0], 64)
+	lat, errLat := strconv.ParseFloat(fields[1], 64)
+	if errLon != nil || errLat != nil {
+		return LonLat{}, ParseError{s}
+	}
+	ll, err := NewLonLat(lon, lat)
+	return *ll, err
+}
@@ -0,0 +1,164 @@
+package nvector
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseLonLatDecimal(t *testing.T) {
+	ll, err := ParseLonLat("12.5, -45.3")
+	if err != nil {
+		t.Error(err)
+	}
+	if !isclose(ll.Lon*180/math.Pi, 12.5, 10) || !isclose(ll.Lat*180/math.Pi, -45.3, 10) {
+		t.Fail()
+	}
+
+	ll, err = ParseLonLat("  -122.25 45.5 ")
+	if err != nil {
+		t.Error(err)
+	}
+	if !isclose(ll.Lon*180/math.Pi, -122.25, 10) || !isclose(ll.Lat*180/math.Pi, 45.5, 10) {
+		t.Fail()
+	}
+}
+
+func TestParseLonLatDMSHemisphere(t *testing.T) {
+	inputs := []string{
+		"45°30'00\"N 122°15'00\"W",
+		"45°30'N, 122°15'W",
+		"122° 15' 00\" W 45° 30' 00\" N",
+		"N45°30' W122°15'",
+		"45d30'N;122d15'W",
+	}
+	for _, s := range inputs {
+		ll, err := ParseLonLat(s)
+		if err != nil {
+			t.Error(s, err)
+			continue
+		}
+		if !isclose(ll.Lon*180/math.Pi, -122.25, 10) || !isclose(ll.Lat*180/math.Pi, 45.5, 10) {
+			t.Error(s)
+		}
+	}
+}
+
+func TestParseLonLatSignedDMS(t *testing.T) {
+	ll, err := ParseLonLat("-122°15'00\" -45°30'36\"")
+	if err != nil {
+		t.Error(err)
+	}
+	if !isclose(ll.Lon*180/math.Pi, -122.25, 10) || !isclose(ll.Lat*180/math.Pi, -45.51, 10) {
+		t.Fail()
+	}
+
+	ll, err = ParseLonLat("100° 30', -0° 30'")
+	if err != nil {
+		t.Error(err)
+	}
+	if !isclose(ll.Lon*180/math.Pi, 100.5, 10) || !isclose(ll.Lat*180/math.Pi, -0.5, 10) {
+		t.Fail()
+	}
+}
+
+func TestParseLonLatInvalidLatitude(t *testing.T) {
+	_, err := ParseLonLat("95°N 10°E")
+	if _, ok := err.(InvalidLatitudeError); !ok {
+		t.Fail()
+	}
+
+	_, err = ParseLonLat("10, 91")
+	if _, ok := err.(InvalidLatitudeError); !ok {
+		t.Fail()
+	}
+}
+
+func TestParseLonLatMalformed(t *testing.T) {
+	inputs := []string{
+		"",
+		"45.5",
+		"abc, def",
+		"1, 2, 3",
+		"45°75'N 122°W",
+		"45°N 50°S",
+		"-45°N 122°W",
+	}
+	for _, s := range inputs {
+		if _, err := ParseLonLat(s); err == nil {
+			t.Error(s)
+		} else if _, ok := err.(ParseError); !ok {
+			t.Error(s, err)
+		}
+	}
+}
+
+func TestDMS(t *testing.T) {
+	ll, _ := NewLonLat(-122.25, 45.5)
+	if s := ll.DMS(); s != "45°30'00\"N 122°15'00\"W" {
+		t.Error(s)
+	}
+
+	ll, _ = NewLonLat(10.0, -33.875)
+	if s := ll.DMS(); s != "33°52'30\"S 10°00'00\"E" {
+		t.Error(s)
+	}
+}
+
+func TestDMSRoundingCarry(t *testing.T) {
+	// 59.9999" rounds up into the next minute, and the next degree
+	ll, _ := NewLonLat(-(10 + 59.0/60 + 59.9999/3600), 20+29.0/60+59.9999/3600)
+	if s := ll.DMS(); s != "20°30'00\"N 11°00'00\"W" {
+		t.Error(s)
+	}
+	if s := ll.DMSWithPrecision(3); s != "20°30'00.000\"N 11°00'00.000\"W" {
+		t.Error(s)
+	}
+	if s := ll.DMSWithPrecision(4); s != "20°29'59.9999\"N 10°59'59.9999\"W" {
+		t.Error(s)
+	}
+}
+
+func TestDMSRoundTrip(t *testing.T) {
+	ll, _ := NewLonLat(151.2093, -33.8688)
+	ll2, err := ParseLonLat(ll.DMSWithPrecision(2))
+	if err != nil {
+		t.Error(err)
+	}
+	if !isclose(ll.Lon, ll2.Lon, 7) || !isclose(ll.Lat, ll2.Lat, 7) {
+		t.Fail()
+	}
+}
+
+func TestWKT(t *testing.T) {
+	ll, _ := NewLonLat(-122.5, 37.75)
+	if s := ll.WKT(); s != "POINT (-122.5 37.75)" {
+		t.Error(s)
+	}
+
+	ll2, _ := NewLonLat(10, -5)
+	if s := LineStringWKT([]LonLat{*ll, *ll2}); s != "LINESTRING (-122.5 37.75, 10 -5)" {
+		t.Error(s)
+	}
+	if s := LineStringWKT(nil); s != "LINESTRING EMPTY" {
+		t.Error(s)
+	}
+}
+
+func TestParseWKTPoint(t *testing.T) {
+	for _, s := range []string{"POINT (-122.5 37.75)", "point(-122.5 37.75)", "  POINT ( -122.5   37.75 ) "} {
+		ll, err := ParseWKTPoint(s)
+		if err != nil {
+			t.Error(s, err)
+		}
+		londeg, latdeg := ll.Degrees()
+		if !isclose(londeg, -122.5, 12) || !isclose(latdeg, 37.75, 12) {
+			t.Error(s)
+		}
+	}
+
+	for _, s := range []string{"POINT (1)", "LINESTRING (1 2)", "POINT 1 2", "POINT (a b)", "POINT (0 91)"} {
+		if _, err := ParseWKTPoint(s); err == nil {
+			t.Errorf("expected error for %q", s)
+		}
+	}
+}
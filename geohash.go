@@ -0,0 +1,81 @@
+package nvector
+
+import (
+	"math"
+	"strings"
+)
+
+const geohashAlphabet = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+// Geohash returns the geohash of the position with *precision* characters,
+// interleaving longitude and latitude bits (longitude first) and encoding each
+// group of five with the standard base-32 alphabet.
+func (ll *LonLat) Geohash(precision int) string {
+	lon := wrapAngle(ll.Lon) * 180.0 / math.Pi
+	lat := ll.Lat * 180.0 / math.Pi
+	lonRange := [2]float64{-180, 180}
+	latRange := [2]float64{-90, 90}
+
+	var hash strings.Builder
+	even := true
+	for hash.Len() < precision {
+		var ch int
+		for bit := 0; bit < 5; bit++ {
+			ch <<= 1
+			if even {
+				if mid := 0.5 * (lonRange[0] + lonRange[1]); lon >= mid {
+					ch |= 1
+					lonRange[0] = mid
+				} else {
+					lonRange[1] = mid
+				}
+			} else {
+				if mid := 0.5 * (latRange[0] + latRange[1]); lat >= mid {
+					ch |= 1
+					latRange[0] = mid
+				} else {
+					latRange[1] = mid
+				}
+			}
+			even = !even
+		}
+		hash.WriteByte(geohashAlphabet[ch])
+	}
+	return hash.String()
+}
+
+// FromGeohash returns the position at the centre of the cell described by a
+// geohash. ParseError is returned if the hash is empty or contains characters
+// outside the geohash alphabet. Upper case characters are accepted.
+func FromGeohash(hash string) (LonLat, error) {
+	if hash == "" {
+		return LonLat{}, ParseError{hash}
+	}
+	lonRange := [2]float64{-180, 180}
+	latRange := [2]float64{-90, 90}
+
+	even := true
+	for _, r := range strings.ToLower(hash) {
+		ch := strings.IndexRune(geohashAlphabet, r)
+		if ch < 0 {
+			return LonLat{}, ParseError{hash}
+		}
+		for bit := 4; bit >= 0; bit-- {
+			rng := &latRange
+			if even {
+				rng = &lonRange
+			}
+			mid := 0.5 * (rng[0] + rng[1])
+			if ch&(1<<uint(bit)) != 0 {
+				rng[0] = mid
+			} else {
+				rng[1] = mid
+			}
+			even = !even
+		}
+	}
+
+	lon := 0.5 * (lonRange[0] + lonRange[1])
+	lat := 0.5 * (latRange[0] + latRange[1])
+	return LonLat{lon * math.Pi / 180.0, lat * math.Pi / 180.0}, nil
+}
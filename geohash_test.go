@@ -0,0 +1,42 @@
+package nvector
+
+import (
+	"math"
+	"testing"
+)
+
+func TestGeohash(t *testing.T) {
+	ll, _ := NewLonLat(-5.6, 42.6)
+	if h := ll.Geohash(5); h != "ezs42" {
+		t.Error(h)
+	}
+
+	ll, _ = NewLonLat(10.40744, 57.64911)
+	if h := ll.Geohash(11); h != "u4pruydqqvj" {
+		t.Error(h)
+	}
+}
+
+func TestFromGeohash(t *testing.T) {
+	ll, err := FromGeohash("ezs42")
+	if err != nil {
+		t.Error(err)
+	}
+	if !isclose(ll.Lon*180/math.Pi, -5.60302734375, 9) || !isclose(ll.Lat*180/math.Pi, 42.60498046875, 9) {
+		t.Fail()
+	}
+
+	ll, err = FromGeohash("U4PRUYDQQVJ")
+	if err != nil {
+		t.Error(err)
+	}
+	if !isclose(ll.Lon*180/math.Pi, 10.40744, 5) || !isclose(ll.Lat*180/math.Pi, 57.64911, 5) {
+		t.Fail()
+	}
+
+	for _, bad := range []string{"", "ezs4a", "ez s4"} {
+		if _, err := FromGeohash(bad); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
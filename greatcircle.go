@@ -0,0 +1,180 @@
+package nvector
+
+import "math"
+
+// GreatCircleNormal returns the unit normal to the plane of the great circle
+// through *nv* and *nv2*, which points to the left of travel from *nv* toward
+// *nv2*. The zero vector is returned when the positions coincide or are
+// antipodal, where no single great circle passes through both. This is
+// decided by the magnitude of their cross product falling below 1e-12, so
+// positions within about 6 µm on the Earth of coinciding or of being
+// antipodal have no great circle, as seen by ClosestOnSegment,
+// IntersectionTol, GreatSmallIntersection and the other functions built on
+// it.
+func (nv *NVector) GreatCircleNormal(nv2 *NVector) Vec3 {
+	axb := cross(&nv.Vec3, &nv2.Vec3)
+	if axb.Magnitude() < 1e-12 {
+		return Vec3{}
+	}
+	return normalize(&axb)
+}
+
+// GreatCircleIntersections returns the two antipodal points where the great
+// circle through *nv1a* and *nv1b* meets the great circle through *nv2a* and
+// *nv2b*, without regard to the segments between the points. The first point
+// returned is the one in the hemisphere centred on *nv1a*. If the circles
+// coincide, or either pair of points doesn't define a great circle,
+// ParallelGreatCirclesError is returned.
+func GreatCircleIntersections(nv1a, nv1b, nv2a, nv2b *NVector) (NVector, NVector, error) {
+	normalA := nv1a.GreatCircleNormal(nv1b)
+	normalB := nv2a.GreatCircleNormal(nv2b)
+	intersection := cross(&normalA, &normalB)
+	if intersection.Magnitude() < 1e-12 {
+		return NVector{}, NVector{}, ParallelGreatCirclesError{}
+	}
+
+	p := normalize(&intersection)
+	if dot(&p, &nv1a.Vec3) < 0 {
+		p = Vec3{-p[0], -p[1], -p[2]}
+	}
+	return NVector{p}, NVector{Vec3{-p[0], -p[1], -p[2]}}, nil
+}
+
+// Collinear returns whether *c* lies within *tolRad* radians of the great
+// circle through *a* and *b*. Coincident or antipodal *a* and *b* lie on
+// every great circle through *c*, so Collinear always returns true for them.
+func Collinear(a, b, c *NVector, tolRad float64) bool {
+	n := a.GreatCircleNormal(b)
+	if n.Magnitude() < 1e-12 {
+		return true
+	}
+	p := normalize(&c.Vec3)
+	return math.Abs(math.Asin(math.Max(-1, math.Min(1, dot(&p, &n))))) <= tolRad
+}
+
+// OnArc returns whether *pt*, assumed to lie on the great circle through *a*
+// and *b*, falls within the minor arc between them. The test is that the
+// angles from *pt* to the two ends sum to the length of the arc, to within
+// *tolRad* radians, so a point beyond an end is accepted if it overshoots by
+// up to half of *tolRad*.
+func OnArc(a, b, pt *NVector, tolRad float64) bool {
+	dab := a.AngleTo(b)
+	dai := a.AngleTo(pt)
+	dbi := b.AngleTo(pt)
+	return math.Abs(dab-dai-dbi) <= tolRad
+}
+
+// SegmentIntersection returns the point where the great circle through *a1*
+// and *a2* crosses the great circle through *b1* and *b2*, and whether that
+// point lies within both segments. Of the two antipodal crossings, the one on
+// both segments is returned if there is one, and otherwise the one nearer the
+// middle of segment a. The error is only non-nil when the great circles are
+// parallel or undefined.
+func SegmentIntersection(a1, a2, b1, b2 *NVector) (pt NVector, onBoth bool, err error) {
+	p1, p2, err := GreatCircleIntersections(a1, a2, b1, b2)
+	if err != nil {
+		return NVector{}, false, err
+	}
+
+	for _, p := range []NVector{p1, p2} {
+		if OnArc(a1, a2, &p, IntersectionToleranceRad) && OnArc(b1, b2, &p, IntersectionToleranceRad) {
+			return p, true, nil
+		}
+	}
+
+	mid := NVector{slerp(&a1.Vec3, &a2.Vec3, 0.5)}
+	if mid.AngleTo(&p1) <= mid.AngleTo(&p2) {
+		return p1, false, nil
+	}
+	return p2, false, nil
+}
+
+// CrossesEquator returns whether the great circle segment from *nv* to *nv2*
+// crosses the equator, whether it does so heading north (an ascending node),
+// and where. A segment starting on the equator counts as crossing it, but one
+// ending there does not, so that a crossing at a vertex shared by consecutive
+// segments is reported once.
+func (nv *NVector) CrossesEquator(nv2 *NVector) (crosses bool, ascending bool, at LonLat) {
+	z1, z2 := nv.Vec3[2], nv2.Vec3[2]
+	switch {
+	case z1 <= 0 && z2 > 0:
+		ascending = true
+	case z1 >= 0 && z2 < 0:
+		ascending = false
+	default:
+		return false, false, LonLat{}
+	}
+
+	// the chord meets the equatorial plane directly below the crossing
+	t := z1 / (z1 - z2)
+	p := NVector{Vec3{nv.Vec3[0] + t*(nv2.Vec3[0]-nv.Vec3[0]),
+		nv.Vec3[1] + t*(nv2.Vec3[1]-nv.Vec3[1]),
+		0}}
+	lon, _ := geodeticLonLat(&p)
+	return true, ascending, LonLat{lon, 0}
+}
+
+// GreatCircleLatitudeCrossings returns the points where the great circle
+// through *a* and *b* reaches latitude *lat* in radians: two where it crosses
+// the parallel, one where the parallel touches it at its highest or lowest
+// latitude, and none if the great circle never reaches the parallel.
+// InvalidLatitudeError is returned if *lat* is outside [-pi/2, pi/2],
+// ParallelGreatCirclesError if *a* and *b* don't define a great circle, and
+// DegenerateGeometryError if the great circle is the equator and *lat* is
+// zero, since every point then lies on the parallel.
+func GreatCircleLatitudeCrossings(a, b *NVector, lat float64) ([]NVector, error) {
+	if _, err := ClampLatitude(lat); err != nil {
+		return nil, err
+	}
+	n := a.GreatCircleNormal(b)
+	if n == (Vec3{}) {
+		return nil, ParallelGreatCirclesError{}
+	}
+
+	// points (cos(lat)cos(lon), cos(lat)sin(lon), sin(lat)) on the great
+	// circle satisfy h·cos(lat)·cos(lon - lon0) = -n_z·sin(lat)
+	h := math.Hypot(n[0], n[1])
+	if h < 1e-15 {
+		if lat == 0 {
+			return nil, DegenerateGeometryError{"great circle coincides with the parallel"}
+		}
+		return []NVector{}, nil
+	}
+	lon0 := math.Atan2(n[1], n[0])
+	c := -n[2] * math.Sin(lat) / (h * math.Cos(lat))
+	switch {
+	case math.Abs(c) > 1+1e-12:
+		return []NVector{}, nil
+	case math.Abs(c) >= 1-1e-12:
+		ll := LonLat{WrapLongitude(lon0 + math.Acos(math.Copysign(1, c))), lat}
+		return []NVector{ll.ToNVector()}, nil
+	}
+	dlon := math.Acos(c)
+	west := LonLat{WrapLongitude(lon0 - dlon), lat}
+	east := LonLat{WrapLongitude(lon0 + dlon), lat}
+	return []NVector{west.ToNVector(), east.ToNVector()}, nil
+}
+
+// MaxLatitude returns the highest latitude reached by the great circle
+// through *nv* and *nv2*, in radians. This is the inclination of the great
+// circle to the equator, and by Clairaut's relation the complement of its
+// azimuth where it crosses the equator. The lowest latitude reached is its
+// negative. The position where it is reached is given by Vertex. NaN is
+// returned if the points don't define a great circle.
+func (nv *NVector) MaxLatitude(nv2 *NVector) float64 {
+	n := nv.GreatCircleNormal(nv2)
+	if n == (Vec3{}) {
+		return math.NaN()
+	}
+	return math.Atan2(math.Hypot(n[0], n[1]), math.Abs(n[2]))
+}
+
+// Vertex returns the northernmost point of the great circle through *nv* and
+// *nv2*, at the latitude given by MaxLatitude. The southernmost point is its
+// antipode. If the great circle is a meridian, the vertex is the north pole,
+// and if it is the equator, or isn't defined, the zero vector is returned.
+func (nv *NVector) Vertex(nv2 *NVector) NVector {
+	n := nv.GreatCircleNormal(nv2)
+	v := Vec3{-n[2] * n[0], -n[2] * n[1], 1 - n[2]*n[2]}
+	return NVector{normalize(&v)}
+}
@@ -0,0 +1,256 @@
+package nvector
+
+import (
+	"math"
+	"testing"
+)
+
+func TestGreatCircleIntersections(t *testing.T) {
+	// the segments don't reach each other, but their great circles meet
+	pts := lonLatRing([][2]float64{{-50, 0}, {-45, 0}, {-40, 5}, {-40, 3}})
+	p1, p2, err := GreatCircleIntersections(&pts[0], &pts[1], &pts[2], &pts[3])
+	if err != nil {
+		t.Error(err)
+	}
+	ll1 := p1.ToLonLat()
+	if !isclose(ll1.Lon*180/math.Pi, -40, 8) || !isclose(ll1.Lat, 0, 8) {
+		t.Fail()
+	}
+	if !isclose(p1.AngleTo(&p2), math.Pi, 12) {
+		t.Fail()
+	}
+
+	if _, err = Intersection(&pts[0], &pts[1], &pts[2], &pts[3]); err == nil {
+		t.Fail()
+	}
+}
+
+func TestGreatCircleIntersectionsCoincident(t *testing.T) {
+	pts := lonLatRing([][2]float64{{0, 0}, {10, 0}, {20, 0}, {30, 0}})
+	_, _, err := GreatCircleIntersections(&pts[0], &pts[1], &pts[2], &pts[3])
+	if _, ok := err.(ParallelGreatCirclesError); !ok {
+		t.Fail()
+	}
+
+	// a repeated point doesn't define a great circle
+	_, _, err = GreatCircleIntersections(&pts[0], &pts[0], &pts[2], &pts[3])
+	if _, ok := err.(ParallelGreatCirclesError); !ok {
+		t.Fail()
+	}
+}
+
+func TestCollinear(t *testing.T) {
+	pts := lonLatRing([][2]float64{{-50, 0}, {-30, 0}, {120, 0}, {-40, 0.001}, {-40, 1}})
+	if !Collinear(&pts[0], &pts[1], &pts[2], 1e-12) {
+		t.Fail()
+	}
+	// 0.001 degrees is about 1.7e-5 radians off the equator
+	if Collinear(&pts[0], &pts[1], &pts[3], 1e-5) || !Collinear(&pts[0], &pts[1], &pts[3], 2e-5) {
+		t.Fail()
+	}
+	if Collinear(&pts[0], &pts[1], &pts[4], 1e-3) {
+		t.Fail()
+	}
+	if !Collinear(&pts[0], &pts[0], &pts[4], 0) {
+		t.Fail()
+	}
+}
+
+func TestSegmentIntersection(t *testing.T) {
+	pts := lonLatRing([][2]float64{{-50, 0}, {-30, 0}, {-40, -5}, {-40, 3}})
+	pt, onBoth, err := SegmentIntersection(&pts[0], &pts[1], &pts[2], &pts[3])
+	if err != nil || !onBoth {
+		t.Fail()
+	}
+	ll := pt.ToLonLat()
+	if !isclose(ll.Lon*180/math.Pi, -40, 8) || !isclose(ll.Lat, 0, 8) {
+		t.Fail()
+	}
+}
+
+func TestSegmentIntersectionOffSegment(t *testing.T) {
+	// the crossing is on segment a, but beyond the end of segment b
+	pts := lonLatRing([][2]float64{{-50, 0}, {-30, 0}, {-40, 5}, {-40, 3}})
+	pt, onBoth, err := SegmentIntersection(&pts[0], &pts[1], &pts[2], &pts[3])
+	if err != nil || onBoth {
+		t.Fail()
+	}
+	ll := pt.ToLonLat()
+	if !isclose(ll.Lon*180/math.Pi, -40, 8) || !isclose(ll.Lat, 0, 8) {
+		t.Fail()
+	}
+
+	// and the reverse
+	pt, onBoth, err = SegmentIntersection(&pts[2], &pts[3], &pts[0], &pts[1])
+	if err != nil || onBoth {
+		t.Fail()
+	}
+	ll = pt.ToLonLat()
+	if !isclose(ll.Lon*180/math.Pi, -40, 8) || !isclose(ll.Lat, 0, 8) {
+		t.Fail()
+	}
+}
+
+func TestSegmentIntersectionParallel(t *testing.T) {
+	pts := lonLatRing([][2]float64{{0, 0}, {10, 0}, {20, 0}, {30, 0}})
+	_, onBoth, err := SegmentIntersection(&pts[0], &pts[1], &pts[2], &pts[3])
+	if err == nil || onBoth {
+		t.Fail()
+	}
+}
+
+func TestCrossesEquator(t *testing.T) {
+	pts := lonLatRing([][2]float64{{10, -5}, {20, 5}, {30, 10}})
+
+	crosses, ascending, at := pts[0].CrossesEquator(&pts[1])
+	if !crosses || !ascending {
+		t.Fail()
+	}
+	if !isclose(at.Lon*180/math.Pi, 15, 2) || at.Lat != 0 {
+		t.Fail()
+	}
+	// the crossing is on the great circle
+	nv := at.ToNVector()
+	normal := cross(&pts[0].Vec3, &pts[1].Vec3)
+	if !isclose(dot(&nv.Vec3, &normal), 0, 12) {
+		t.Fail()
+	}
+
+	crosses, ascending, at2 := pts[1].CrossesEquator(&pts[0])
+	if !crosses || ascending || !isclose(at2.Lon, at.Lon, 12) {
+		t.Fail()
+	}
+
+	if crosses, _, _ = pts[1].CrossesEquator(&pts[2]); crosses {
+		t.Fail()
+	}
+}
+
+func TestCrossesEquatorAtVertex(t *testing.T) {
+	pts := lonLatRing([][2]float64{{-150, -5}, {-160, 0}, {-170, 5}})
+	crosses1, _, _ := pts[0].CrossesEquator(&pts[1])
+	crosses2, ascending, at := pts[1].CrossesEquator(&pts[2])
+	if crosses1 || !crosses2 || !ascending {
+		t.Fail()
+	}
+	if !isclose(at.Lon*180/math.Pi, -160, 10) {
+		t.Fail()
+	}
+}
+
+func TestGreatCircleLatitudeCrossings(t *testing.T) {
+	R := 6371e3
+	origin, _ := NewLonLat(0, 0)
+	a := origin.ToNVector()
+	b := a.Forward(0.25*math.Pi, 1000e3, R)
+	axb := cross(&a.Vec3, &b.Vec3)
+	n := normalize(&axb)
+
+	// a great circle crossing the equator at 45 degrees reaches 45 degrees
+	for _, latdeg := range []float64{30, -30, 0} {
+		lat := latdeg * math.Pi / 180
+		pts, err := GreatCircleLatitudeCrossings(&a, &b, lat)
+		if err != nil {
+			t.Error(err)
+		}
+		if len(pts) != 2 {
+			t.Fatal(latdeg, len(pts))
+		}
+		for _, p := range pts {
+			ll := p.ToLonLat()
+			if !isclose(ll.Lat, lat, 12) || !isclose(dot(&p.Vec3, &n), 0, 12) {
+				t.Fail()
+			}
+		}
+	}
+
+	pts, _ := GreatCircleLatitudeCrossings(&a, &b, 0.25*math.Pi)
+	if len(pts) != 1 {
+		t.Error(len(pts))
+	} else if ll := pts[0].ToLonLat(); !isclose(ll.Lon, 0.5*math.Pi, 6) {
+		t.Fail()
+	}
+
+	pts, _ = GreatCircleLatitudeCrossings(&a, &b, 1.0)
+	if pts == nil || len(pts) != 0 {
+		t.Fail()
+	}
+
+	// the equator lies on every point of the zero parallel
+	east, _ := NewLonLat(30, 0)
+	nve := east.ToNVector()
+	if _, err := GreatCircleLatitudeCrossings(&a, &nve, 0); err == nil {
+		t.Fail()
+	}
+}
+
+func TestMaxLatitude(t *testing.T) {
+	R := 6371e3
+	origin, _ := NewLonLat(-20, 0)
+	a := origin.ToNVector()
+	b := a.Forward(0.25*math.Pi, 1000e3, R)
+	if !isclose(a.MaxLatitude(&b), 0.25*math.Pi, 12) {
+		t.Fail()
+	}
+	if !isclose(b.MaxLatitude(&a), 0.25*math.Pi, 12) {
+		t.Fail()
+	}
+
+	v := a.Vertex(&b)
+	ll := v.ToLonLat()
+	if !isclose(ll.Lat, 0.25*math.Pi, 12) || !isclose(ll.Lon*180/math.Pi, 70, 10) {
+		t.Error(ll.String())
+	}
+
+	// crossing the equator at an azimuth of 30 degrees reaches 60 degrees
+	b = a.Forward(math.Pi/6, 1000e3, R)
+	if !isclose(a.MaxLatitude(&b), math.Pi/3, 12) {
+		t.Error(a.MaxLatitude(&b))
+	}
+
+	// a meridian reaches the pole
+	c, _ := NewLonLat(-20, 10)
+	nvc := c.ToNVector()
+	if !isclose(a.MaxLatitude(&nvc), 0.5*math.Pi, 12) {
+		t.Fail()
+	}
+	if v := a.Vertex(&nvc); !isclose(v.Vec3[2], 1, 12) {
+		t.Fail()
+	}
+}
+
+func TestGreatCircleNormal(t *testing.T) {
+	pts := lonLatRing([][2]float64{{0, 0}, {90, 0}, {-90, 0}, {37, 12}})
+	n := pts[0].GreatCircleNormal(&pts[1])
+	if !isclose(n[0], 0, 12) || !isclose(n[1], 0, 12) || !isclose(n[2], 1, 12) {
+		t.Error(n)
+	}
+	// the normal is perpendicular to both points
+	n = pts[0].GreatCircleNormal(&pts[3])
+	if !isclose(n.Magnitude(), 1, 12) || !isclose(dot(&n, &pts[0].Vec3), 0, 12) ||
+		!isclose(dot(&n, &pts[3].Vec3), 0, 12) {
+		t.Error(n)
+	}
+	if (pts[1].GreatCircleNormal(&pts[1]) != Vec3{}) || (pts[1].GreatCircleNormal(&pts[2]) != Vec3{}) {
+		t.Fail()
+	}
+}
+
+func TestOnArc(t *testing.T) {
+	pts := lonLatRing([][2]float64{{10, 0}, {20, 0}, {15, 0}, {25, 0}, {-170, 0}})
+	if !OnArc(&pts[0], &pts[1], &pts[2], 1e-12) {
+		t.Fail()
+	}
+	if !OnArc(&pts[0], &pts[1], &pts[0], 1e-12) || !OnArc(&pts[0], &pts[1], &pts[1], 1e-12) {
+		t.Fail()
+	}
+	// beyond the end, and on the antipodal side of the great circle
+	if OnArc(&pts[0], &pts[1], &pts[3], 1e-9) || OnArc(&pts[0], &pts[1], &pts[4], 1e-9) {
+		t.Fail()
+	}
+	// a point overshooting the end by 1e-6 radians needs a tolerance of 2e-6
+	over := NVector{slerp(&pts[0].Vec3, &pts[1].Vec3, 1+1e-6/pts[0].AngleTo(&pts[1]))}
+	if OnArc(&pts[0], &pts[1], &over, 1.9e-6) || !OnArc(&pts[0], &pts[1], &over, 2.1e-6) {
+		t.Fail()
+	}
+}
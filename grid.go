@@ -0,0 +1,71 @@
+package nvector
+
+import "math"
+
+// GlobalGrid returns a regular grid of positions covering the globe, spaced by
+// *latStepDeg* degrees in latitude and *lonStepDeg* degrees in longitude.
+// Parallels run from the south pole to the north pole inclusive, and
+// meridians from -180 up to (but excluding) 180 degrees. Each pole is
+// represented by a single point. Nil is returned if either step is not
+// positive.
+func GlobalGrid(latStepDeg, lonStepDeg float64) []LonLat {
+	if latStepDeg <= 0 || lonStepDeg <= 0 {
+		return nil
+	}
+	nlat := int(math.Floor(180.0/latStepDeg + 1e-9))
+	nlon := int(math.Ceil(360.0/lonStepDeg - 1e-9))
+
+	grid := make([]LonLat, 0, (nlat+1)*nlon)
+	for i := 0; i <= nlat; i++ {
+		latdeg := -90.0 + float64(i)*latStepDeg
+		lat := latdeg * math.Pi / 180.0
+		if math.Abs(latdeg) > 90.0-1e-9 {
+			grid = append(grid, LonLat{0, math.Copysign(0.5*math.Pi, latdeg)})
+			continue
+		}
+		for j := 0; j < nlon; j++ {
+			londeg := -180.0 + float64(j)*lonStepDeg
+			grid = append(grid, LonLat{londeg * math.Pi / 180.0, lat})
+		}
+	}
+	return grid
+}
+
+// EqualAreaGrid returns *n* positions distributed over the globe so that each
+// occupies roughly the same area, using a Fibonacci lattice. Unlike
+// GlobalGrid, points do not crowd together near the poles.
+func EqualAreaGrid(n int) []LonLat {
+	if n <= 0 {
+		return nil
+	}
+	goldenAngle := math.Pi * (3 - math.Sqrt(5))
+
+	grid := make([]LonLat, n)
+	for i := 0; i < n; i++ {
+		z := 1 - (2*float64(i)+1)/float64(n)
+		lon := math.Mod(float64(i)*goldenAngle, 2*math.Pi)
+		if lon >= math.Pi {
+			lon -= 2 * math.Pi
+		}
+		grid[i] = LonLat{lon, math.Asin(z)}
+	}
+	return grid
+}
+
+// DistanceToGraticule returns the distance on a sphere with radius *R* from
+// *ll* to the nearest line of a graticule with parallels every *latStepDeg*
+// degrees and meridians every *lonStepDeg* degrees, both counted from zero.
+func DistanceToGraticule(ll LonLat, latStepDeg, lonStepDeg, R float64) float64 {
+	latStep := latStepDeg * math.Pi / 180.0
+	lonStep := lonStepDeg * math.Pi / 180.0
+
+	// distance along the meridian to the nearest parallel
+	parallel := math.Round(ll.Lat/latStep) * latStep
+	dParallel := math.Abs(ll.Lat-parallel) * R
+
+	// cross-track distance to the great circle of the nearest meridian
+	meridian := math.Round(ll.Lon/lonStep) * lonStep
+	dMeridian := math.Asin(math.Abs(math.Cos(ll.Lat)*math.Sin(ll.Lon-meridian))) * R
+
+	return math.Min(dParallel, dMeridian)
+}
@@ -0,0 +1,97 @@
+package nvector
+
+import (
+	"math"
+	"testing"
+)
+
+func TestGlobalGrid(t *testing.T) {
+	grid := GlobalGrid(30, 45)
+	// 5 interior parallels of 8 points each, plus the two poles
+	if len(grid) != 42 {
+		t.Fail()
+	}
+	if !isclose(grid[0].Lat, -0.5*math.Pi, 12) {
+		t.Fail()
+	}
+	if !isclose(grid[len(grid)-1].Lat, 0.5*math.Pi, 12) {
+		t.Fail()
+	}
+	if !isclose(grid[1].Lon, -math.Pi, 12) {
+		t.Fail()
+	}
+	for _, ll := range grid {
+		if ll.Lon < -math.Pi || ll.Lon >= math.Pi {
+			t.Fail()
+		}
+	}
+}
+
+func TestGlobalGridInvalid(t *testing.T) {
+	if GlobalGrid(0, 10) != nil {
+		t.Fail()
+	}
+}
+
+func TestEqualAreaGrid(t *testing.T) {
+	n := 1000
+	grid := EqualAreaGrid(n)
+	if len(grid) != n {
+		t.Fail()
+	}
+
+	nvs := make([]NVector, n)
+	for i := range grid {
+		nvs[i] = grid[i].ToNVector()
+	}
+
+	// nearest-neighbour spacing should be nearly uniform
+	var dmin, dmax, dmean float64
+	dmin = math.Inf(1)
+	for i := range nvs {
+		nearest := math.Inf(1)
+		for j := range nvs {
+			if i == j {
+				continue
+			}
+			d := nvs[i].SphericalDistance(&nvs[j], 1.0)
+			if d < nearest {
+				nearest = d
+			}
+		}
+		dmin = math.Min(dmin, nearest)
+		dmax = math.Max(dmax, nearest)
+		dmean += nearest / float64(n)
+	}
+
+	if (dmin/dmean < 0.85) || (dmax/dmean > 1.15) {
+		t.Fail()
+	}
+}
+
+func TestDistanceToGraticule(t *testing.T) {
+	R := 6371000.0
+
+	// 0.1 degrees from the 10E meridian but 0.3 from the 50N parallel
+	ll, _ := NewLonLat(10.1, 49.7)
+	d := DistanceToGraticule(*ll, 1, 5, R)
+	pt := ll.ToNVector()
+	onMeridian, _ := NewLonLat(10, 49.7)
+	foot := onMeridian.ToNVector()
+	if d >= pt.SphericalDistance(&foot, R) || d < 0.99*pt.SphericalDistance(&foot, R) {
+		t.Error(d)
+	}
+
+	// nearer the parallel than the meridian
+	ll, _ = NewLonLat(12.4, 50.05)
+	d = DistanceToGraticule(*ll, 1, 5, R)
+	if !isclose(d, 0.05*math.Pi/180*R, 6) {
+		t.Error(d)
+	}
+
+	// on a graticule intersection
+	ll, _ = NewLonLat(-15, 30)
+	if !isclose(DistanceToGraticule(*ll, 10, 15, R), 0, 6) {
+		t.Fail()
+	}
+}
@@ -0,0 +1,332 @@
+package nvector
+
+import "math"
+
+// SteeringLeg is a single constant-bearing leg of a steering plan, running
+// along the rhumb line from Waypoint to the waypoint of the next leg. Bearing
+// is in radians clockwise from north, and Distance is the length of the rhumb
+// line in the units of the radius used to construct the plan.
+type SteeringLeg struct {
+	Waypoint NVector
+	Bearing  float64
+	Distance float64
+}
+
+// SteeringPlan divides the great circle from *a* to *b* into legs that can
+// each be steered on a constant bearing. A new waypoint is placed wherever the
+// great circle course has drifted by more than *bearingToleranceDeg* degrees
+// from the bearing of the current leg, so that legs are short where the course
+// changes quickly and long where it doesn't. Each leg reports its starting
+// waypoint, and the constant bearing and distance on a sphere with radius *R*
+// of the rhumb line to the next waypoint, so that holding the bearing for the
+// distance arrives there. A tolerance that isn't positive would need
+// infinitely many legs, and gives nil.
+func SteeringPlan(a, b *NVector, bearingToleranceDeg, R float64) []SteeringLeg {
+	if !(bearingToleranceDeg > 0) {
+		return nil
+	}
+	start := normalize(&a.Vec3)
+	end := normalize(&b.Vec3)
+	axis := cross(&start, &end)
+	total := math.Atan2(axis.Magnitude(), dot(&start, &end))
+	if total == 0 {
+		return []SteeringLeg{{NVector{start}, 0, 0}}
+	}
+	tolerance := bearingToleranceDeg * math.Pi / 180.0
+
+	// course along the great circle at fraction *f* of the route
+	course := func(f float64) float64 {
+		if f >= 1 {
+			return wrapAngle(bearing(&end, &start) + math.Pi)
+		}
+		p := slerp(&start, &end, f)
+		return bearing(&p, &end)
+	}
+
+	// walk the route in small steps to bracket each change of bearing, then
+	// refine by bisection
+	const nsteps = 1000
+	step := 1.0 / nsteps
+
+	var waypoints []float64
+	f0 := 0.0
+	for f0 < 1 {
+		c0 := course(f0)
+		f1 := f0 + step
+		for f1 < 1 && math.Abs(wrapAngle(course(f1)-c0)) <= tolerance {
+			f1 += step
+		}
+		if f1 >= 1 && math.Abs(wrapAngle(course(1)-c0)) <= tolerance {
+			f1 = 1
+		} else {
+			lo, hi := f1-step, math.Min(f1, 1)
+			for i := 0; i < 40; i++ {
+				mid := 0.5 * (lo + hi)
+				if math.Abs(wrapAngle(course(mid)-c0)) <= tolerance {
+					lo = mid
+				} else {
+					hi = mid
+				}
+			}
+			f1 = math.Max(lo, f0+1e-9)
+		}
+
+		waypoints = append(waypoints, f0)
+		f0 = f1
+	}
+
+	// steer the rhumb line between each pair of waypoints
+	plan := make([]SteeringLeg, len(waypoints))
+	for i, f := range waypoints {
+		from, to := NVector{slerp(&start, &end, f)}, NVector{end}
+		if i < len(waypoints)-1 {
+			to = NVector{slerp(&start, &end, waypoints[i+1])}
+		}
+		llFrom, llTo := from.ToLonLat(), to.ToLonLat()
+		dlat, dlon, q := rhumbLine(&llFrom, &llTo)
+		plan[i] = SteeringLeg{
+			Waypoint: from,
+			Bearing:  math.Atan2(q*dlon, dlat),
+			Distance: math.Sqrt(dlat*dlat+q*q*dlon*dlon) * R,
+		}
+	}
+	return plan
+}
+
+// CourseLeg is a leg of a voyage steered on a great circle course, with
+// Bearing in radians clockwise from north at the start of the leg and
+// Distance in the units of the sphere radius
+type CourseLeg struct {
+	Bearing  float64
+	Distance float64
+}
+
+// DeadReckon returns the positions reached by sailing each of *legs* in turn
+// from *start* on a sphere with radius *R*, beginning with *start* itself,
+// normalized. Positions are renormalized after each leg so that rounding
+// errors don't accumulate over long voyages.
+func DeadReckon(start *NVector, legs []CourseLeg, R float64) []NVector {
+	pos := NVector{normalize(&start.Vec3)}
+	track := make([]NVector, 1, len(legs)+1)
+	track[0] = pos
+	for _, leg := range legs {
+		next := pos.Forward(leg.Bearing, leg.Distance, R)
+		pos = NVector{normalize(&next.Vec3)}
+		track = append(track, pos)
+	}
+	return track
+}
+
+// Isochrone returns a ring of *bearingSamples* positions bounding the region
+// reachable from *origin* on a sphere with radius *R*, spaced evenly in
+// bearing starting from due north. Along each bearing the reach is
+// *maxDistance* scaled by the relative speed *speed*(bearing), so that
+// currents or winds give a non-circular region. A nil *speed* means uniform
+// travel, giving a geodesic circle of radius *maxDistance*. The ring is open:
+// its first position is not repeated at the end.
+func Isochrone(origin *NVector, maxDistance, R float64, bearingSamples int, speed func(bearing float64) float64) []NVector {
+	if bearingSamples < 1 {
+		return nil
+	}
+	ring := make([]NVector, bearingSamples)
+	for i := range ring {
+		az := 2 * math.Pi * float64(i) / float64(bearingSamples)
+		reach := maxDistance
+		if speed != nil {
+			reach *= math.Max(speed(az), 0)
+		}
+		ring[i] = origin.Forward(az, reach, R)
+	}
+	return ring
+}
+
+// ClosestApproach returns the time in [0, *tMax*] at which two vessels are
+// nearest each other, and their separation at that time. Each vessel starts
+// from its position and holds a great circle course on its initial bearing
+// (in radians) at constant speed, with distances on a sphere with radius *R*
+// and speeds in units of *R* per unit time. The separation is sampled over the
+// window and the nearest sample refined by golden section search.
+func ClosestApproach(p1 *NVector, az1, speed1 float64, p2 *NVector, az2, speed2 float64, tMax, R float64) (tMin, distMin float64) {
+	separation := func(t float64) float64 {
+		a := p1.Forward(az1, speed1*t, R)
+		b := p2.Forward(az2, speed2*t, R)
+		return a.SphericalDistance(&b, R)
+	}
+	if tMax <= 0 {
+		return 0, separation(0)
+	}
+
+	const nsamples = 200
+	step := tMax / nsamples
+	best := 0
+	bestDist := separation(0)
+	for i := 1; i <= nsamples; i++ {
+		if d := separation(float64(i) * step); d < bestDist {
+			best, bestDist = i, d
+		}
+	}
+
+	// the minimum lies within a step of the nearest sample
+	lo := math.Max(0, float64(best-1)*step)
+	hi := math.Min(tMax, float64(best+1)*step)
+	invphi := (math.Sqrt(5) - 1) / 2
+	x1 := hi - invphi*(hi-lo)
+	x2 := lo + invphi*(hi-lo)
+	d1, d2 := separation(x1), separation(x2)
+	for i := 0; i < 60; i++ {
+		if d1 < d2 {
+			hi, x2, d2 = x2, x1, d1
+			x1 = hi - invphi*(hi-lo)
+			d1 = separation(x1)
+		} else {
+			lo, x1, d1 = x1, x2, d2
+			x2 = lo + invphi*(hi-lo)
+			d2 = separation(x2)
+		}
+	}
+
+	tMin, distMin = float64(best)*step, bestDist
+	mid := 0.5 * (lo + hi)
+	if d := separation(mid); d < distMin {
+		tMin, distMin = mid, d
+	}
+	return tMin, distMin
+}
+
+// RouteAround returns a route from *a* to *b* that avoids the circular
+// exclusion zone of great circle radius *radius* around *center*, on a sphere
+// with radius *R*. If the direct great circle segment doesn't come within
+// *radius* of *center*, the route is just [*a*, *b*]. Otherwise it is a
+// two-leg detour [*a*, waypoint, *b*] whose legs run along the great circles
+// tangent to the zone from *a* and from *b*, passing the zone on whichever
+// side is shorter. DegenerateGeometryError is returned if either end lies
+// within the zone.
+func RouteAround(a, b, center *NVector, radius, R float64) ([]NVector, error) {
+	closest := ClosestOnSegment(center, a, b)
+	if center.SphericalDistance(&closest, R) >= radius {
+		return []NVector{*a, *b}, nil
+	}
+
+	rho := radius / R
+	da, db := center.AngleTo(a), center.AngleTo(b)
+	if da <= rho || db <= rho {
+		return nil, DegenerateGeometryError{"route ends within the exclusion zone"}
+	}
+
+	// in the right spherical triangle formed by the centre, an end and its
+	// tangent point, the angle at the centre has cosine tan(rho)/tan(d)
+	gammaA := math.Acos(math.Tan(rho) / math.Tan(da))
+	gammaB := math.Acos(math.Tan(rho) / math.Tan(db))
+	thetaA := bearing(&center.Vec3, &a.Vec3)
+	thetaB := bearing(&center.Vec3, &b.Vec3)
+
+	var route []NVector
+	best := math.Inf(1)
+	for _, side := range []float64{1, -1} {
+		ta := center.Forward(thetaA+side*gammaA, radius, R)
+		tb := center.Forward(thetaB-side*gammaB, radius, R)
+		p1, p2, err := GreatCircleIntersections(a, &ta, b, &tb)
+		if err != nil {
+			continue
+		}
+		waypoint := p1
+		if dot(&p2.Vec3, &center.Vec3) > dot(&p1.Vec3, &center.Vec3) {
+			waypoint = p2
+		}
+		if d := a.AngleTo(&waypoint) + waypoint.AngleTo(b); d < best {
+			route, best = []NVector{*a, waypoint, *b}, d
+		}
+	}
+	if route == nil {
+		return nil, DegenerateGeometryError{"no detour around the exclusion zone"}
+	}
+	return route, nil
+}
+
+// horizonAngle returns the angle at the Earth's centre between an observer at
+// *height* above a sphere of radius *R* and its geometric horizon
+func horizonAngle(height, R float64) float64 {
+	if height <= 0 {
+		return 0
+	}
+	return math.Acos(R / (R + height))
+}
+
+// HorizonDistance returns the distance over the surface from the point below
+// an observer at *height* meters to the observer's geometric horizon,
+// treating the Earth as a sphere with the mean radius of *ellps* and ignoring
+// atmospheric refraction.
+func HorizonDistance(height float64, ellps *Ellipsoid) float64 {
+	R := ellps.meanRadius()
+	return horizonAngle(height, R) * R
+}
+
+// LineOfSight returns whether an observer *hA* meters above *a* and a target
+// *hB* meters above *b* can see each other over a spherical Earth with the
+// mean radius of *ellps*. This is the case when the pair are no farther apart
+// than the sum of their horizon distances. Refraction and terrain are ignored.
+func LineOfSight(a, b *NVector, hA, hB float64, ellps *Ellipsoid) bool {
+	R := ellps.meanRadius()
+	return a.AngleTo(b) <= horizonAngle(hA, R)+horizonAngle(hB, R)
+}
+
+// mercatorLat returns the isometric latitude, the northing of *lat* on a
+// Mercator projection of the unit sphere
+func mercatorLat(lat float64) float64 {
+	return math.Log(math.Tan(0.25*math.Pi + 0.5*lat))
+}
+
+// rhumbLine returns the change of latitude and of longitude, the latter
+// taken the short way around, of the rhumb line from *a* to *b*, and the
+// ratio of east-west distance to change in longitude along it
+func rhumbLine(a, b *LonLat) (dlat, dlon, q float64) {
+	dlat = b.Lat - a.Lat
+	dlon = wrapAngle(b.Lon - a.Lon)
+	dpsi := mercatorLat(b.Lat) - mercatorLat(a.Lat)
+	if math.Abs(dpsi) > 1e-12 {
+		q = dlat / dpsi
+	} else {
+		q = math.Cos(a.Lat)
+	}
+	return dlat, dlon, q
+}
+
+// ComparePlan returns routes from *a* to *b* along the great circle and
+// along the rhumb line (loxodrome), each sampled at *n* evenly spaced
+// waypoints including both ends, and their lengths on a sphere with radius
+// *R*. The rhumb line holds a constant bearing, taking the shorter way around
+// in longitude, so the difference in lengths is the cost of avoiding the
+// course changes of the great circle.
+func ComparePlan(a, b *NVector, n int, R float64) (gc []NVector, rhumb []NVector, gcDist, rhumbDist float64) {
+	gc = GreatCirclePoints(a, b, n)
+	gcDist = a.SphericalDistance(b, R)
+
+	llA, llB := a.ToLonLat(), b.ToLonLat()
+	dlat, dlon, q := rhumbLine(&llA, &llB)
+	rhumbDist = math.Sqrt(dlat*dlat+q*q*dlon*dlon) * R
+
+	if n < 1 {
+		return gc, nil, gcDist, rhumbDist
+	}
+	rhumb = make([]NVector, n)
+	psiA := mercatorLat(llA.Lat)
+	dpsi := mercatorLat(llB.Lat) - psiA
+	for i := range rhumb {
+		f := 0.0
+		if n > 1 {
+			f = float64(i) / float64(n-1)
+		}
+		lat := llA.Lat + f*dlat
+		lon := llA.Lon + f*dlon
+		if math.Abs(dpsi) > 1e-12 {
+			lon = llA.Lon + dlon*(mercatorLat(lat)-psiA)/dpsi
+		}
+		ll := LonLat{WrapLongitude(lon), lat}
+		rhumb[i] = ll.ToNVector()
+	}
+	rhumb[0] = *a
+	if n > 1 {
+		rhumb[n-1] = *b
+	}
+	return gc, rhumb, gcDist, rhumbDist
+}
@@ -0,0 +1,299 @@
+package nvector
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSteeringPlanShort(t *testing.T) {
+	ll1, _ := NewLonLat(-123.0, 49.0)
+	ll2, _ := NewLonLat(-122.9, 49.05)
+	nv1 := ll1.ToNVector()
+	nv2 := ll2.ToNVector()
+	R := 6371000.0
+
+	plan := SteeringPlan(&nv1, &nv2, 1.0, R)
+	if len(plan) != 1 {
+		t.Fatal(len(plan))
+	}
+	_, _, _, rhumbDist := ComparePlan(&nv1, &nv2, 0, R)
+	if !isclose(plan[0].Distance, rhumbDist, 6) {
+		t.Fail()
+	}
+	// over a short leg the rhumb bearing is nearly the great circle course
+	if math.Abs(wrapAngle(plan[0].Bearing-bearing(&nv1.Vec3, &nv2.Vec3))) > 1e-3 {
+		t.Error(plan[0].Bearing)
+	}
+}
+
+func TestSteeringPlanLong(t *testing.T) {
+	// Vancouver to Tokyo
+	ll1, _ := NewLonLat(-123.1, 49.3)
+	ll2, _ := NewLonLat(139.7, 35.7)
+	nv1 := ll1.ToNVector()
+	nv2 := ll2.ToNVector()
+	R := 6371000.0
+
+	plan := SteeringPlan(&nv1, &nv2, 5.0, R)
+	if len(plan) < 5 {
+		t.Fatal(len(plan))
+	}
+
+	var total float64
+	for i, leg := range plan {
+		total += leg.Distance
+		next := nv2
+		if i < len(plan)-1 {
+			next = plan[i+1].Waypoint
+		}
+
+		// each leg's bearing lies between the great circle courses at its
+		// ends, which differ by the tolerance
+		courseStart := bearing(&leg.Waypoint.Vec3, &next.Vec3)
+		courseEnd := wrapAngle(bearing(&next.Vec3, &leg.Waypoint.Vec3) + math.Pi)
+		if i < len(plan)-1 && !isclose(math.Abs(wrapAngle(courseEnd-courseStart)), 5.0*math.Pi/180, 3) {
+			t.Error(i, wrapAngle(courseEnd-courseStart)*180/math.Pi)
+		}
+		if wrapAngle(leg.Bearing-courseStart)*wrapAngle(courseEnd-leg.Bearing) < 0 {
+			t.Error(i, leg.Bearing, courseStart, courseEnd)
+		}
+
+		// holding the bearing for the distance arrives at the next waypoint
+		ll, llNext := leg.Waypoint.ToLonLat(), next.ToLonLat()
+		lat := ll.Lat + leg.Distance/R*math.Cos(leg.Bearing)
+		lon := ll.Lon + math.Tan(leg.Bearing)*(mercatorLat(lat)-mercatorLat(ll.Lat))
+		if !isclose(lat, llNext.Lat, 9) || !isclose(wrapAngle(lon-llNext.Lon), 0, 9) {
+			t.Error(i, "misses the next waypoint")
+		}
+	}
+
+	// the rhumb lines are slightly longer than the great circle
+	gc := nv1.SphericalDistance(&nv2, R)
+	if total < gc || total > 1.001*gc {
+		t.Error(total, gc)
+	}
+	if !isclose(plan[0].Waypoint.SphericalDistance(&nv1, R), 0, 6) {
+		t.Fail()
+	}
+}
+
+func TestSteeringPlanInvalidTolerance(t *testing.T) {
+	ll1, _ := NewLonLat(-123.1, 49.3)
+	ll2, _ := NewLonLat(139.7, 35.7)
+	nv1 := ll1.ToNVector()
+	nv2 := ll2.ToNVector()
+	for _, tol := range []float64{0, -1, math.NaN()} {
+		if plan := SteeringPlan(&nv1, &nv2, tol, 6371000.0); plan != nil {
+			t.Error(tol, len(plan))
+		}
+	}
+}
+
+func TestIsochroneUniform(t *testing.T) {
+	ll, _ := NewLonLat(-63.6, 44.6)
+	origin := ll.ToNVector()
+	R := 6371000.0
+
+	ring := Isochrone(&origin, 250000, R, 36, nil)
+	if len(ring) != 36 {
+		t.Fatal(len(ring))
+	}
+	for i := range ring {
+		if !isclose(origin.SphericalDistance(&ring[i], R), 250000, 5) {
+			t.Error(i)
+		}
+	}
+	if !PointInPolygon(&origin, ring) {
+		t.Fail()
+	}
+}
+
+func TestIsochroneAnisotropic(t *testing.T) {
+	ll, _ := NewLonLat(-63.6, 44.6)
+	origin := ll.ToNVector()
+	R := 6371000.0
+
+	// a current setting toward the east doubles progress in that direction
+	speed := func(bearing float64) float64 { return 1.5 + 0.5*math.Sin(bearing) }
+	ring := Isochrone(&origin, 100000, R, 4, speed)
+	expected := []float64{150000, 200000, 150000, 100000}
+	for i := range ring {
+		if !isclose(origin.SphericalDistance(&ring[i], R), expected[i], 5) {
+			t.Error(i)
+		}
+	}
+}
+
+func TestHorizonDistance(t *testing.T) {
+	ellps := Ellipsoid{6378137.0, 6356752.314245}
+	R := ellps.meanRadius()
+
+	// an observer at 2 m sees about 5 km
+	if !isclose(HorizonDistance(2, &ellps), math.Sqrt(2*R*2), 0) {
+		t.Fail()
+	}
+	if HorizonDistance(0, &ellps) != 0 {
+		t.Fail()
+	}
+	if HorizonDistance(100, &ellps) <= HorizonDistance(10, &ellps) {
+		t.Fail()
+	}
+}
+
+func TestLineOfSight(t *testing.T) {
+	ellps := Ellipsoid{6378137.0, 6356752.314245}
+	ll, _ := NewLonLat(-63.6, 44.6)
+	a := ll.ToNVector()
+	R := ellps.meanRadius()
+
+	// a 100 m mast and a 10 m mast see each other at just under their
+	// combined horizon distances
+	reach := HorizonDistance(100, &ellps) + HorizonDistance(10, &ellps)
+	near := a.Forward(1.0, reach-100, R)
+	far := a.Forward(1.0, reach+100, R)
+	if !LineOfSight(&a, &near, 100, 10, &ellps) {
+		t.Fail()
+	}
+	if LineOfSight(&a, &far, 100, 10, &ellps) {
+		t.Fail()
+	}
+	if !LineOfSight(&far, &a, 10, 120, &ellps) {
+		t.Fail()
+	}
+}
+
+func TestClosestApproach(t *testing.T) {
+	R := 6371e3
+	pos, _ := NewLonLat(-4, 50)
+	meet := pos.ToNVector()
+
+	// two vessels converging on the same point after an hour, one from the
+	// south heading north and one from the west heading east
+	south := meet.Forward(math.Pi, 10*3600, R)
+	west := meet.Forward(-0.5*math.Pi, 5*3600, R)
+	azWest := bearing(&west.Vec3, &meet.Vec3)
+	tMin, distMin := ClosestApproach(&south, 0, 10, &west, azWest, 5, 7200, R)
+	if !isclose(tMin, 3600, 3) || !isclose(distMin, 0, 3) {
+		t.Error(tMin, distMin)
+	}
+
+	// heading apart, the closest approach is at the start
+	tMin, distMin = ClosestApproach(&south, math.Pi, 10, &west, azWest+math.Pi, 5, 7200, R)
+	if tMin != 0 || !isclose(distMin, south.SphericalDistance(&west, R), 6) {
+		t.Error(tMin, distMin)
+	}
+}
+
+func TestRouteAround(t *testing.T) {
+	R := 6371e3
+	pts := lonLatRing([][2]float64{{0, 0}, {10, 0}, {5, 0.5}, {5, 3}})
+	a, b := pts[0], pts[1]
+	radius := 200e3
+
+	// a zone clear of the route leaves it direct
+	route, err := RouteAround(&a, &b, &pts[3], radius, R)
+	if err != nil || len(route) != 2 || route[0] != a || route[1] != b {
+		t.Error("direct route", route, err)
+	}
+
+	// a zone straddling the route is passed on its nearer, southern side
+	center := pts[2]
+	route, err = RouteAround(&a, &b, &center, radius, R)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(route) != 3 || route[0] != a || route[2] != b {
+		t.Fatal(route)
+	}
+	if route[1].ToLonLat().Lat >= 0 {
+		t.Error("detour should pass south of the zone", route[1].ToLonLat())
+	}
+	for i := 1; i < len(route); i++ {
+		d := DistanceToPolyline(&center, route[i-1:i+1], R)
+		if !isclose(d, radius, 3) {
+			t.Error("leg", i, "should be tangent to the zone", d)
+		}
+	}
+
+	if _, err := RouteAround(&center, &b, &center, radius, R); err == nil {
+		t.Error("expected an error for a route starting in the zone")
+	}
+}
+
+func TestDeadReckon(t *testing.T) {
+	R := 6371e3
+	pos, _ := NewLonLat(0, 0)
+	start := pos.ToNVector()
+
+	// a box north, east, south, west around the equator returns home
+	legs := []CourseLeg{{0, 1000}, {0.5 * math.Pi, 1000}, {math.Pi, 1000}, {-0.5 * math.Pi, 1000}}
+	track := DeadReckon(&start, legs, R)
+	if len(track) != 5 || track[0].AngleTo(&start) > 1e-15 {
+		t.Fatal(len(track))
+	}
+	if !isclose(track[1].SphericalDistance(&start, R), 1000, 6) {
+		t.Fail()
+	}
+	if !isclose(track[4].SphericalDistance(&start, R), 0, 3) {
+		t.Error(track[4].SphericalDistance(&start, R))
+	}
+	for _, p := range track {
+		if !p.IsUnit(1e-15) {
+			t.Fail()
+		}
+	}
+
+	if track := DeadReckon(&start, nil, R); len(track) != 1 {
+		t.Fail()
+	}
+
+	// the start is normalized like the rest of the track
+	scaled := NVector{Vec3{2 * start.Vec3[0], 2 * start.Vec3[1], 2 * start.Vec3[2]}}
+	if track := DeadReckon(&scaled, legs, R); !track[0].IsUnit(1e-15) || track[0].AngleTo(&start) > 1e-15 {
+		t.Error(track[0])
+	}
+}
+
+func TestComparePlan(t *testing.T) {
+	R := 6371e3
+	llA, _ := NewLonLat(-74, 40.7)
+	llB, _ := NewLonLat(-0.1, 51.5)
+	a, b := llA.ToNVector(), llB.ToNVector()
+
+	gc, rhumb, gcDist, rhumbDist := ComparePlan(&a, &b, 20, R)
+	if len(gc) != 20 || len(rhumb) != 20 {
+		t.Fatal(len(gc), len(rhumb))
+	}
+	if rhumb[0] != a || rhumb[19] != b {
+		t.Fail()
+	}
+	if !(gcDist < rhumbDist) {
+		t.Error("great circle should be shorter", gcDist, rhumbDist)
+	}
+	// New York to London is about 5570 km by great circle and 5800 km by rhumb
+	if math.Abs(gcDist-5570e3) > 10e3 || math.Abs(rhumbDist-5800e3) > 20e3 {
+		t.Error(gcDist, rhumbDist)
+	}
+
+	// the rhumb line holds a constant bearing, which is seen between closely
+	// spaced waypoints
+	_, rhumb, _, _ = ComparePlan(&a, &b, 2000, R)
+	az := bearing(&rhumb[0].Vec3, &rhumb[1].Vec3)
+	for i := 1; i < len(rhumb)-1; i++ {
+		if !isclose(bearing(&rhumb[i].Vec3, &rhumb[i+1].Vec3), az, 3) {
+			t.Error(i)
+		}
+	}
+	if !isclose(PathLength(rhumb, R), rhumbDist, -3) {
+		t.Error(PathLength(rhumb, R), rhumbDist)
+	}
+
+	// along the equator the two coincide
+	llC, _ := NewLonLat(10, 0)
+	llD, _ := NewLonLat(40, 0)
+	c, d := llC.ToNVector(), llD.ToNVector()
+	_, _, gcDist, rhumbDist = ComparePlan(&c, &d, 5, R)
+	if !isclose(gcDist, rhumbDist, 3) {
+		t.Fail()
+	}
+}
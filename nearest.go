@@ -0,0 +1,90 @@
+package nvector
+
+import (
+	"math"
+	"sort"
+)
+
+// NearestNeighbor returns the index of the candidate closest to *query* and
+// its distance on a sphere with radius *R*. When several candidates are
+// equally close, the first of them is returned. For an empty slice of
+// candidates, the index is -1 and the distance +Inf.
+func NearestNeighbor(query *NVector, candidates []NVector, R float64) (idx int, dist float64) {
+	idx, dist = -1, math.Inf(1)
+	for i := range candidates {
+		if d := query.SphericalDistance(&candidates[i], R); d < dist {
+			idx, dist = i, d
+		}
+	}
+	return idx, dist
+}
+
+// siteIndex finds the nearest of a fixed set of sites to query positions. It
+// is the extension point for spatial indexes; linearSites is the simple case.
+type siteIndex interface {
+	nearest(q *NVector) int
+}
+
+// linearSites is a siteIndex that compares every site, holding the sites
+// normalized so that the nearest has the largest dot product with the query
+type linearSites []Vec3
+
+func newLinearSites(sites []NVector) linearSites {
+	idx := make(linearSites, len(sites))
+	for i := range sites {
+		idx[i] = normalize(&sites[i].Vec3)
+	}
+	return idx
+}
+
+func (sites linearSites) nearest(q *NVector) int {
+	best, bestDot := -1, math.Inf(-1)
+	for i := range sites {
+		if d := dot(&q.Vec3, &sites[i]); d > bestDot {
+			best, bestDot = i, d
+		}
+	}
+	return best
+}
+
+// NearestSites returns, for each of *queries*, the index of the nearest of
+// *sites* by angular distance, which classifies the queries by the spherical
+// Voronoi cell of the sites containing them. Ties go to the earlier site, and
+// every index is -1 when there are no sites.
+func NearestSites(queries, sites []NVector) []int {
+	var index siteIndex = newLinearSites(sites)
+	nearest := make([]int, len(queries))
+	for i := range queries {
+		nearest[i] = index.nearest(&queries[i])
+	}
+	return nearest
+}
+
+// SortByBearing returns the indices of *pts* ordered by their initial bearing
+// from *center*, clockwise from north, starting at north. Bearings are
+// rounded to the nearest nanoradian, and points with the same rounded bearing
+// are ordered by increasing distance from *center*, so points coinciding with
+// it come first. *pts* isn't modified.
+func SortByBearing(center *NVector, pts []NVector) []int {
+	c := normalize(&center.Vec3)
+	az := make([]float64, len(pts))
+	idx := make([]int, len(pts))
+	for i := range pts {
+		az[i] = bearing(&c, &pts[i].Vec3)
+		if az[i] < 0 {
+			az[i] += 2 * math.Pi
+		}
+		// quantize, so that nearly equal bearings compare as equal while
+		// keeping the ordering transitive
+		az[i] = math.Round(az[i]*1e9) / 1e9
+		idx[i] = i
+	}
+	sort.SliceStable(idx, func(i, j int) bool {
+		a, b := idx[i], idx[j]
+		if az[a] != az[b] {
+			return az[a] < az[b]
+		}
+		return center.AngleTo(&pts[a]) < center.AngleTo(&pts[b])
+	})
+	return idx
+}
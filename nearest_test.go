@@ -0,0 +1,80 @@
+package nvector
+
+import (
+	"math"
+	"testing"
+)
+
+func TestNearestNeighbor(t *testing.T) {
+	R := 6371e3
+	pos, _ := NewLonLat(-123.1, 49.3)
+	query := pos.ToNVector()
+	candidates := []NVector{
+		query.Forward(0.0, 5000, R),
+		query.Forward(1.0, 1200, R),
+		query.Forward(2.0, 3000, R),
+	}
+	// an exact tie goes to the earlier candidate
+	candidates = append(candidates, candidates[1])
+	idx, dist := NearestNeighbor(&query, candidates, R)
+	if idx != 1 {
+		t.Errorf("expected candidate 1, got %d", idx)
+	}
+	if !isclose(dist, 1200, 6) {
+		t.Fail()
+	}
+}
+
+func TestNearestNeighborEmpty(t *testing.T) {
+	pos, _ := NewLonLat(0, 0)
+	query := pos.ToNVector()
+	idx, dist := NearestNeighbor(&query, nil, 6371e3)
+	if idx != -1 || !math.IsInf(dist, 1) {
+		t.Fail()
+	}
+}
+
+func TestSortByBearing(t *testing.T) {
+	R := 6371e3
+	pos, _ := NewLonLat(10, -35)
+	center := pos.ToNVector()
+	pts := []NVector{
+		center.Forward(-0.5*math.Pi, 1000, R), // west
+		center.Forward(0.1, 2000, R),
+		center.Forward(math.Pi, 1000, R),
+		center.Forward(0.1, 500, R), // same bearing, closer
+		center.Forward(0.5*math.Pi, 3000, R),
+	}
+	order := SortByBearing(&center, pts)
+	expected := []int{3, 1, 4, 2, 0}
+	for i := range expected {
+		if order[i] != expected[i] {
+			t.Fatalf("expected %v, got %v", expected, order)
+		}
+	}
+	if len(SortByBearing(&center, nil)) != 0 {
+		t.Fail()
+	}
+}
+
+func TestNearestSites(t *testing.T) {
+	R := 6371e3
+	sites := lonLatRing([][2]float64{{0, 0}, {90, 0}, {0, 90}, {-120, -30}})
+	queries := lonLatRing([][2]float64{{10, 5}, {80, -20}, {45, 80}, {-100, -45}, {179, 0}})
+	nearest := NearestSites(queries, sites)
+	for i := range queries {
+		idx, _ := NearestNeighbor(&queries[i], sites, R)
+		if nearest[i] != idx {
+			t.Errorf("query %d: expected site %d, got %d", i, idx, nearest[i])
+		}
+	}
+	if nearest[0] != 0 || nearest[2] != 2 {
+		t.Fail()
+	}
+
+	for _, idx := range NearestSites(queries, nil) {
+		if idx != -1 {
+			t.Fail()
+		}
+	}
+}
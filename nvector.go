@@ -13,6 +13,14 @@ import (
 	"math"
 )
 
+// Radii of the Earth in meters, for use with the spherical methods
+const (
+	// EarthRadiusMean is the IUGG mean radius of the WGS84 ellipsoid
+	EarthRadiusMean = 6371008.8
+	// EarthRadiusEquatorial is the WGS84 semi-major axis
+	EarthRadiusEquatorial = 6378137.0
+)
+
 type ILonLat interface {
 	ToNVector() INVector
 }
@@ -44,6 +52,21 @@ type LonLat struct {
 	Lat float64
 }
 
+// LonLatHeight is a geographical position with a height in meters above the
+// ellipsoid
+type LonLatHeight struct {
+	Lon    float64
+	Lat    float64
+	Height float64
+}
+
+// Position is a horizontal position given by an n-vector, with a height in
+// meters above the surface. A depth is a negative height.
+type Position struct {
+	NVector
+	Height float64
+}
+
 // Ellipsoid represents a geographical ellipsoid in terms of its major and
 // minor axes
 type Ellipsoid struct {
@@ -65,8 +88,48 @@ func (e NoIntersectionError) Error() string {
 	return fmt.Sprintf("no intersection")
 }
 
-func cross(u, v *Vec3) *Vec3 {
-	return &Vec3{u[1]*v[2] - u[2]*v[1], u[2]*v[0] - u[0]*v[2], u[0]*v[1] - u[1]*v[0]}
+// ParallelGreatCirclesError is returned when two great circles coincide, or
+// when a pair of points is too close or too nearly antipodal to define a great
+// circle, so that no unique intersection exists
+type ParallelGreatCirclesError struct {
+}
+
+func (e ParallelGreatCirclesError) Error() string {
+	return fmt.Sprintf("great circles are parallel or undefined")
+}
+
+// DegenerateGeometryError is returned when the input to a geometric operation
+// is too degenerate (e.g. empty, coincident, or antipodal) to give a
+// meaningful result
+type DegenerateGeometryError struct {
+	Reason string
+}
+
+func (e DegenerateGeometryError) Error() string {
+	return fmt.Sprintf("degenerate geometry: %s", e.Reason)
+}
+
+// InvalidRadiusError is returned when a sphere radius is zero, negative, or
+// NaN
+type InvalidRadiusError struct {
+	Radius float64
+}
+
+func (e InvalidRadiusError) Error() string {
+	return fmt.Sprintf("invalid radius: %f", e.Radius)
+}
+
+// LengthMismatchError is returned when paired slices have different lengths
+type LengthMismatchError struct {
+	Expected, Actual int
+}
+
+func (e LengthMismatchError) Error() string {
+	return fmt.Sprintf("length mismatch: expected %d, got %d", e.Expected, e.Actual)
+}
+
+func cross(u, v *Vec3) Vec3 {
+	return Vec3{u[1]*v[2] - u[2]*v[1], u[2]*v[0] - u[0]*v[2], u[0]*v[1] - u[1]*v[0]}
 }
 
 func dot(u, v *Vec3) float64 {
@@ -77,6 +140,66 @@ func (v *Vec3) Magnitude() float64 {
 	return math.Sqrt(v[0]*v[0] + v[1]*v[1] + v[2]*v[2])
 }
 
+// normalize returns a unit vector parallel to *v*, or the zero vector if *v*
+// has no length
+func normalize(v *Vec3) Vec3 {
+	m := v.Magnitude()
+	if m == 0 {
+		return Vec3{}
+	}
+	return Vec3{v[0] / m, v[1] / m, v[2] / m}
+}
+
+// wrapAngle returns an angle equivalent to *x* in the range [-pi, pi)
+func wrapAngle(x float64) float64 {
+	if x >= -math.Pi && x < math.Pi {
+		return x
+	}
+	x = math.Mod(x+math.Pi, 2*math.Pi)
+	if x < 0 {
+		x += 2 * math.Pi
+	}
+	return x - math.Pi
+}
+
+// northEast returns unit vectors pointing north and east in the plane tangent
+// to *v*. At the poles, where east is undefined, east is taken as the
+// direction of 90⁰E longitude.
+func northEast(v *Vec3) (Vec3, Vec3) {
+	e := cross(&Vec3{0, 0, 1}, v)
+	east := normalize(&e)
+	if east == (Vec3{}) {
+		east = Vec3{0, 1, 0}
+	}
+	n := cross(v, &east)
+	north := normalize(&n)
+	return north, east
+}
+
+// bearing returns the initial great circle course from *u* toward *v*,
+// clockwise from north in the range (-pi, pi]
+func bearing(u, v *Vec3) float64 {
+	north, east := northEast(u)
+	return math.Atan2(dot(v, &east), dot(v, &north))
+}
+
+// slerp returns the position a fraction *frac* of the way along the great
+// circle arc from *u* to *v*. Fractions outside [0, 1] extrapolate along the
+// same great circle.
+func slerp(u, v *Vec3, frac float64) Vec3 {
+	a := normalize(u)
+	b := normalize(v)
+	axb := cross(&a, &b)
+	theta := math.Atan2(axb.Magnitude(), dot(&a, &b))
+	if math.Sin(theta) < 1e-12 {
+		p := Vec3{a[0] + frac*(b[0]-a[0]), a[1] + frac*(b[1]-a[1]), a[2] + frac*(b[2]-a[2])}
+		return normalize(&p)
+	}
+	ca := math.Sin((1-frac)*theta) / math.Sin(theta)
+	cb := math.Sin(frac*theta) / math.Sin(theta)
+	return Vec3{ca*a[0] + cb*b[0], ca*a[1] + cb*b[1], ca*a[2] + cb*b[2]}
+}
+
 func (m *Matrix3) Mult(v *Vec3) Vec3 {
 	var p Vec3
 	p[0] = v[0]*m[0][0] + v[1]*m[0][1] + v[2]*m[0][2]
@@ -93,17 +216,53 @@ func (m *Matrix3) Transpose() Matrix3 {
 	return tr
 }
 
+// WrapLongitude returns the longitude equivalent to *radians* in the range
+// [-pi, pi)
+func WrapLongitude(radians float64) float64 {
+	return wrapAngle(radians)
+}
+
+// ClampLatitude returns *radians* limited to the range of valid latitudes,
+// [-pi/2, pi/2]. If it had to be clamped, InvalidLatitudeError is returned
+// along with the clamped value.
+func ClampLatitude(radians float64) (float64, error) {
+	switch {
+	case radians < -0.5*math.Pi:
+		return -0.5 * math.Pi, InvalidLatitudeError{radians * 180.0 / math.Pi}
+	case radians > 0.5*math.Pi:
+		return 0.5 * math.Pi, InvalidLatitudeError{radians * 180.0 / math.Pi}
+	case math.IsNaN(radians):
+		return radians, InvalidLatitudeError{radians}
+	}
+	return radians, nil
+}
+
 func NewLonLat(londeg float64, latdeg float64) (*LonLat, error) {
-	lon := londeg * math.Pi / 180.0
-	lat := latdeg * math.Pi / 180.0
-	lon = math.Mod((lon+math.Pi), 2*math.Pi) - math.Pi
-	if (lat < -0.5*math.Pi) || (lat > 0.5*math.Pi) {
+	lon := WrapLongitude(londeg * math.Pi / 180.0)
+	lat, err := ClampLatitude(latdeg * math.Pi / 180.0)
+	if err != nil {
 		lonlat := new(LonLat)
 		return lonlat, InvalidLatitudeError{latdeg}
 	}
 	return &LonLat{lon, lat}, nil
 }
 
+// NewLonLatRadians returns a LonLat from a longitude and latitude already in
+// radians, wrapping the longitude and validating the latitude as NewLonLat
+// does
+func NewLonLatRadians(lonRad, latRad float64) (*LonLat, error) {
+	lat, err := ClampLatitude(latRad)
+	if err != nil {
+		return new(LonLat), err
+	}
+	return &LonLat{WrapLongitude(lonRad), lat}, nil
+}
+
+// Degrees returns the longitude and latitude in degrees
+func (ll *LonLat) Degrees() (lonDeg, latDeg float64) {
+	return ll.Lon * 180.0 / math.Pi, ll.Lat * 180.0 / math.Pi
+}
+
 // ToNVector returns a Cartesian position vector.
 func (ll *LonLat) ToNVector() NVector {
 	z := math.Sin(ll.Lat)
@@ -113,19 +272,35 @@ func (ll *LonLat) ToNVector() NVector {
 }
 
 func (ll *LonLat) String() string {
-	londeg := ll.Lon * 180.0 / math.Pi
-	latdeg := ll.Lat * 180.0 / math.Pi
+	londeg, latdeg := ll.Degrees()
 	return fmt.Sprintf("(%.6f, %.6f)", londeg, latdeg)
 }
 
 // ToLonLat returns a LonLat struct, where lon: [-pi, pi) and lat: [-pi/2, pi/2].
+// At the poles the longitude is undefined. The latitude is then exactly
+// ±pi/2, and an n-vector exactly at a pole, (0, 0, ±1), gives a longitude of
+// zero. N-vectors made by LonLat.ToNVector keep vanishingly small horizontal
+// components at the poles, so these return their original longitude, but
+// that shouldn't be relied on after further computation. See IsPole.
 func (nv *NVector) ToLonLat() LonLat {
 	lat := math.Atan2(nv.Vec3[2], math.Sqrt(nv.Vec3[0]*nv.Vec3[0]+nv.Vec3[1]*nv.Vec3[1]))
-	lon := math.Atan2(nv.Vec3[1], nv.Vec3[0])
-	lon = math.Mod((lon+0.5*math.Pi), math.Pi) - 0.5*math.Pi
+	lon := WrapLongitude(math.Atan2(nv.Vec3[1], nv.Vec3[0]))
 	return LonLat{lon, lat}
 }
 
+// IsPole returns whether the n-vector lies within *tol* radians of either
+// pole, where longitude is undefined or poorly conditioned
+func (nv *NVector) IsPole(tol float64) bool {
+	colat := math.Atan2(math.Hypot(nv.Vec3[0], nv.Vec3[1]), math.Abs(nv.Vec3[2]))
+	return colat <= tol
+}
+
+// IsUnit returns whether the magnitude of the n-vector is within *tol* of
+// one, as most operations assume
+func (nv *NVector) IsUnit(tol float64) bool {
+	return math.Abs(nv.Vec3.Magnitude()-1) <= tol
+}
+
 // ToPVector returns a surface-normal vector, given an ellipsoid.
 func (nv *NVector) ToPVector(ellps *Ellipsoid) PVector {
 	absq := ellps.a * ellps.a / (ellps.b * ellps.b)
@@ -135,7 +310,26 @@ func (nv *NVector) ToPVector(ellps *Ellipsoid) PVector {
 	return PVector{Vec3{coeff * absq * nv.Vec3[0], coeff * absq * nv.Vec3[1], coeff * nv.Vec3[2]}}
 }
 
-// ToNVector returns a Cartesian position vector, given an ellipsoid.
+// ToECEFSphere returns the Earth-centered position of *nv* on the surface of a
+// sphere with radius *R*, which is simply the n-vector scaled by *R*. Use
+// ToPVector for an ellipsoid.
+func (nv *NVector) ToECEFSphere(R float64) Vec3 {
+	n := normalize(&nv.Vec3)
+	return Vec3{R * n[0], R * n[1], R * n[2]}
+}
+
+// NVectorFromECEFSphere returns the n-vector of the Earth-centered position
+// *v* on a spherical Earth, which is the direction of *v*. The inverse of
+// ToECEFSphere.
+func NVectorFromECEFSphere(v Vec3) NVector {
+	return NVector{normalize(&v)}
+}
+
+// ToNVector returns a Cartesian position vector, given an ellipsoid. The
+// n-vector is the normal to the ellipsoid through the position, which needn't
+// lie on the surface, using the closed-form solution of Gade (2010). Each
+// component takes the sign of the corresponding component of the p-vector,
+// so that positions in every octant round trip through ToPVector.
 func (pv *PVector) ToNVector(ellps *Ellipsoid) NVector {
 	eccen := math.Sqrt(1 - ellps.b*ellps.b/(ellps.a*ellps.a))
 	eccen2 := eccen * eccen
@@ -154,17 +348,20 @@ func (pv *PVector) ToNVector(ellps *Ellipsoid) NVector {
 	coeff := 1.0 / math.Sqrt(d*d+pv.Vec3[2]*pv.Vec3[2])
 	kke2 := k / (k + eccen2)
 	return NVector{
-		Vec3{-coeff * kke2 * pv.Vec3[0],
-			-coeff * kke2 * pv.Vec3[1],
+		Vec3{coeff * kke2 * pv.Vec3[0],
+			coeff * kke2 * pv.Vec3[1],
 			coeff * pv.Vec3[2]}}
 }
 
 // RotationMatrix returns the 3x3 matrix relating the Earth-centered
 // non-singular coordinate frame to the North-East-Down singular coordinate
-// frame.
+// frame. Its columns are the north, east, and down directions at *nv* in
+// Earth-centered coordinates, so it transforms NED vectors to Earth-centered
+// vectors, and its transpose the reverse. LocalFrame names the two
+// directions.
 func (nv *NVector) RotationMatrix() Matrix3 {
 	east := cross(&Vec3{0, 0, 1}, &nv.Vec3)
-	north := cross(&nv.Vec3, east)
+	north := cross(&nv.Vec3, &east)
 
 	a := north[0] / north.Magnitude()
 	b := east[0] / east.Magnitude()
@@ -178,32 +375,196 @@ func (nv *NVector) RotationMatrix() Matrix3 {
 	return Matrix3{[3]float64{a, b, c}, [3]float64{d, e, f}, [3]float64{g, h, i}}
 }
 
+// AngleTo returns the angle in radians subtended at the centre of the Earth
+// between *nv* and another NVector, in the range [0, pi]
+func (nv *NVector) AngleTo(nv2 *NVector) float64 {
+	c := cross(&nv.Vec3, &nv2.Vec3)
+	return math.Atan2(c.Magnitude(), dot(&nv.Vec3, &nv2.Vec3))
+}
+
 // SphericalDistance returns the distance from another NVector on a sphere with
-// radius *R*
+// radius *R*. The angle is found from atan2(|a×b|, a·b), which is accurate at
+// all separations. At short range the dot product approaches one and
+// carries little information, but the magnitude of the cross product remains
+// proportional to the separation.
 func (nv *NVector) SphericalDistance(nv2 *NVector, R float64) float64 {
-	s_ab := math.Atan2(cross(&nv.Vec3, &nv2.Vec3).Magnitude(),
-		dot(&nv.Vec3, &nv2.Vec3)) * R
+	s_ab := nv.AngleTo(nv2) * R
 	return s_ab
 }
 
-// Azimuth returns the azimuth and back azimuth from one NVector to another
-// along an ellipse
-func (nv *NVector) Azimuth(nv2 *NVector, ellps *Ellipsoid) float64 {
+// InitialBearing returns the initial great circle course from *nv* toward
+// *nv2*, in radians clockwise from north in the range (-pi, pi], found from
+// the north and east directions at *nv* as Forward uses. Unlike Azimuth, it
+// needs no ellipsoid. At the poles, where the bearing is undefined, east is
+// taken as the direction of 90⁰E longitude, as in Forward.
+func (nv *NVector) InitialBearing(nv2 *NVector) float64 {
+	a := normalize(&nv.Vec3)
+	return bearing(&a, &nv2.Vec3)
+}
+
+// BearingsTo returns the initial bearing from *nv* to each of *targets*, as
+// InitialBearing does
+func (nv *NVector) BearingsTo(targets []NVector) []float64 {
+	a := normalize(&nv.Vec3)
+	bearings := make([]float64, len(targets))
+	for i := range targets {
+		bearings[i] = bearing(&a, &targets[i].Vec3)
+	}
+	return bearings
+}
+
+// InverseSpherical returns the distance between *nv* and *nv2* on a sphere
+// with radius *R*, the initial bearing from *nv* toward *nv2*, and the back
+// bearing from *nv2* toward *nv*, with bearings in radians clockwise from
+// north in the range (-pi, pi]. The bearings are zero for coincident
+// positions. The bearings are found from the same cross product as the
+// distance, rather than from the north and east directions at each end.
+func (nv *NVector) InverseSpherical(nv2 *NVector, R float64) (dist, bearing12, bearing21 float64) {
+	a := normalize(&nv.Vec3)
+	b := normalize(&nv2.Vec3)
+	axb := cross(&a, &b)
+	dist = math.Atan2(axb.Magnitude(), dot(&a, &b)) * R
+	if axb == (Vec3{}) && dot(&a, &b) > 0 {
+		return dist, 0, 0
+	}
+
+	// the course at *u* toward *v* is along n × u, where n is the great
+	// circle normal shared by both ends, and its north and east components
+	// are proportional to (n × u)_z and n_z. At the poles, or when the ends
+	// are antipodal, there is no shared normal to use.
+	course := func(u, v, n *Vec3) float64 {
+		t := cross(n, u)
+		if *n == (Vec3{}) || (u[0] == 0 && u[1] == 0) {
+			return bearing(u, v)
+		}
+		return math.Atan2(n[2], t[2])
+	}
+	bxa := Vec3{-axb[0], -axb[1], -axb[2]}
+	bearing12 = course(&a, &b, &axb)
+	bearing21 = course(&b, &a, &bxa)
+	return dist, bearing12, bearing21
+}
+
+// SphericalDistanceLawCosines returns the distance from another NVector on a
+// sphere with radius *R* using the spherical law of cosines, acos(a·b). It is
+// provided for comparison with other tools. Because the cosine is flat near
+// zero, positions closer than about ten centimetres on the Earth become
+// indistinguishable, and SphericalDistance should be preferred.
+func (nv *NVector) SphericalDistanceLawCosines(nv2 *NVector, R float64) float64 {
+	a := normalize(&nv.Vec3)
+	b := normalize(&nv2.Vec3)
+	return math.Acos(math.Max(-1, math.Min(1, dot(&a, &b)))) * R
+}
+
+// HaversineDistance returns the distance between two positions on a sphere
+// with radius *R* using the haversine formula, computed directly from
+// longitudes and latitudes. It is provided for comparison with tools that use
+// it; SphericalDistance gives the same result from n-vectors and is accurate
+// for nearly antipodal positions, where the haversine formula is not.
+func HaversineDistance(ll1, ll2 LonLat, R float64) float64 {
+	h := hav(ll2.Lat-ll1.Lat) + math.Cos(ll1.Lat)*math.Cos(ll2.Lat)*hav(ll2.Lon-ll1.Lon)
+	return 2 * math.Asin(math.Sqrt(math.Min(1, h))) * R
+}
+
+// DeltaNED returns the North-East-Down displacement from *nv* to *nv2*, in
+// the local frame at *nv*, given an ellipsoid.
+func (nv *NVector) DeltaNED(nv2 *NVector, ellps *Ellipsoid) Vec3 {
 	pv1 := nv.ToPVector(ellps)
 	pv2 := nv2.ToPVector(ellps)
 	delta_E := Vec3{pv2.Vec3[0] - pv1.Vec3[0], pv2.Vec3[1] - pv1.Vec3[1], pv2.Vec3[2] - pv1.Vec3[2]}
 
-	rotMat_EN := nv.RotationMatrix()
-	rotMat_NE := rotMat_EN.Transpose()
-	delta_N := rotMat_NE.Mult(&delta_E)
+	frame := nv.LocalFrame()
+	return frame.ECEFtoLocal(delta_E)
+}
+
+// SmallDistance returns the distance from another NVector on a sphere with
+// radius *R*, computed from the chord between the two positions as
+// 2*asin(chord/2), which is the haversine formula expressed in n-vectors.
+// SphericalDistance is already accurate for small separations, since the
+// magnitude of the cross product shrinks in proportion to the angle rather
+// than underflowing; SmallDistance avoids the cross product altogether and
+// serves as an independent check at sub-metre scales. It loses accuracy for
+// nearly antipodal positions, where SphericalDistance should be preferred.
+func (nv *NVector) SmallDistance(nv2 *NVector, R float64) float64 {
+	a := normalize(&nv.Vec3)
+	b := normalize(&nv2.Vec3)
+	chord := Vec3{a[0] - b[0], a[1] - b[1], a[2] - b[2]}
+	return 2 * math.Asin(0.5*chord.Magnitude()) * R
+}
+
+// WithinDistance returns whether *other* is no more than *maxDist* from *nv*
+// on a sphere with radius *R*. This is equivalent to testing
+// a·b >= cos(maxDist/R), avoiding the cost of computing the distance itself,
+// which makes it the fast path for filtering large sets of points. The test
+// is made on the squared chord length, |a-b|² <= 4sin²(maxDist/2R), which
+// unlike the dot product keeps its precision for thresholds of a few
+// centimetres. Both n-vectors must be of unit length. No position is within
+// a negative distance, so false is returned when *maxDist* is negative.
+func (nv *NVector) WithinDistance(other *NVector, maxDist, R float64) bool {
+	angle := maxDist / R
+	if angle < 0 {
+		return false
+	}
+	if angle >= math.Pi {
+		return true
+	}
+	dx := nv.Vec3[0] - other.Vec3[0]
+	dy := nv.Vec3[1] - other.Vec3[1]
+	dz := nv.Vec3[2] - other.Vec3[2]
+	halfChord := math.Sin(0.5 * angle)
+	return dx*dx+dy*dy+dz*dz <= 4*halfChord*halfChord
+}
+
+// WithinRadius returns whether *nv* lies within *radius* of *center* on a
+// sphere with radius *R*, comparing their spherical distance. The boundary is
+// inclusive, so a position exactly *radius* away is within it. Unlike
+// WithinDistance, *nv* and *center* need not be normalized.
+func (nv *NVector) WithinRadius(center *NVector, radius, R float64) bool {
+	return nv.SphericalDistance(center, R) <= radius
+}
+
+// SphericalDistanceDefault returns the distance in meters from another NVector
+// on a sphere with the Earth's mean radius
+func (nv *NVector) SphericalDistanceDefault(nv2 *NVector) float64 {
+	return nv.SphericalDistance(nv2, EarthRadiusMean)
+}
+
+// Azimuth returns the azimuth and back azimuth from one NVector to another
+// along an ellipse
+func (nv *NVector) Azimuth(nv2 *NVector, ellps *Ellipsoid) float64 {
+	delta_N := nv.DeltaNED(nv2, ellps)
 	return math.Atan2(delta_N[1], delta_N[0])
 }
 
+// ToENU returns the East-North-Up components of the displacement from *nv*
+// to *target*, in the local tangent plane at *nv*, given an ellipsoid.
+func (nv *NVector) ToENU(target *NVector, ellps *Ellipsoid) Vec3 {
+	delta_N := nv.DeltaNED(target, ellps)
+	return Vec3{delta_N[1], delta_N[0], -delta_N[2]}
+}
+
+// FromENU returns the NVector position displaced from *nv* by the
+// East-North-Up vector *enu*, given an ellipsoid. The inverse of ToENU. The
+// up component affects only where the horizontal position ends up.
+func (nv *NVector) FromENU(enu Vec3, ellps *Ellipsoid) NVector {
+	delta_N := Vec3{enu[1], enu[0], -enu[2]}
+	frame := nv.LocalFrame()
+	delta_E := frame.LocalToECEF(delta_N)
+
+	pv := nv.ToPVector(ellps)
+	pv.Vec3[0] += delta_E[0]
+	pv.Vec3[1] += delta_E[1]
+	pv.Vec3[2] += delta_E[2]
+	return pv.ToNVector(ellps)
+}
+
 // Forward returns the NVector position arrived at by moving in an azimuthal
-// direction for a given distance along an ellipse
+// direction for a given distance along an ellipse. A negative distance moves
+// in the opposite direction. The radius is not checked: a zero radius gives
+// a result of NaNs, and a negative one reverses the direction of travel. Use
+// ForwardChecked where the radius may be invalid.
 func (nv *NVector) Forward(az, distance, radius float64) NVector {
-	east := cross(&nv.Vec3, &Vec3{0, 0, -1})
-	north := cross(&nv.Vec3, east)
+	north, east := northEast(&nv.Vec3)
 
 	cos_az := math.Cos(az)
 	sin_az := math.Sin(az)
@@ -221,6 +582,23 @@ func (nv *NVector) Forward(az, distance, radius float64) NVector {
 	return NVector{resultant}
 }
 
+// OffsetPerpendicular returns the position *distance* from *nv* at right
+// angles to a course on *bearing*. A positive distance offsets to the right
+// of the direction of travel (bearing + pi/2) and a negative distance to the
+// left.
+func (nv *NVector) OffsetPerpendicular(bearing, distance, R float64) NVector {
+	return nv.Forward(bearing+0.5*math.Pi, distance, R)
+}
+
+// ForwardChecked is Forward with validation of the radius, returning
+// InvalidRadiusError if *radius* is not positive
+func (nv *NVector) ForwardChecked(az, distance, radius float64) (NVector, error) {
+	if !(radius > 0) {
+		return NVector{}, InvalidRadiusError{radius}
+	}
+	return nv.Forward(az, distance, radius), nil
+}
+
 func interpLinear(x, x0, x1, y0, y1 float64) float64 {
 	return (x-x0)/(x1-x0)*(y1-y0) + y0
 }
@@ -229,50 +607,127 @@ func interpLinear(x, x0, x1, y0, y1 float64) float64 {
 // between two other NVectors. *frac* is the fractional distance between *nv*
 // and *nv2*.
 func (nv *NVector) Interpolate(nv2 *NVector, frac float64) NVector {
-	result := new(NVector)
-	result.Vec3[0] = interpLinear(frac, 0, 1, nv.Vec3[0], nv2.Vec3[0])
-	result.Vec3[1] = interpLinear(frac, 0, 1, nv.Vec3[1], nv2.Vec3[1])
-	result.Vec3[2] = interpLinear(frac, 0, 1, nv.Vec3[2], nv2.Vec3[2])
-	return *result
+	return nv.InterpolateValue(*nv2, frac)
+}
+
+// InterpolateValue is Interpolate taking and returning values, so that it
+// can be used in bulk processing without heap allocation
+func (nv NVector) InterpolateValue(nv2 NVector, frac float64) NVector {
+	return NVector{Vec3{
+		interpLinear(frac, 0, 1, nv.Vec3[0], nv2.Vec3[0]),
+		interpLinear(frac, 0, 1, nv.Vec3[1], nv2.Vec3[1]),
+		interpLinear(frac, 0, 1, nv.Vec3[2], nv2.Vec3[2])}}
+}
+
+// InterpolateMany returns the positions at each of the fractions *fracs* of
+// the way along the great circle arc from *nv* to *nv2*, as slerp does for a
+// single position. The angle between the endpoints is computed once for all
+// fractions. Fractions outside [0, 1] extrapolate along the same great circle
+// beyond *nv* or *nv2*.
+func (nv *NVector) InterpolateMany(nv2 *NVector, fracs []float64) []NVector {
+	a := normalize(&nv.Vec3)
+	b := normalize(&nv2.Vec3)
+	axb := cross(&a, &b)
+	theta := math.Atan2(axb.Magnitude(), dot(&a, &b))
+	sintheta := math.Sin(theta)
+
+	pts := make([]NVector, len(fracs))
+	for i, frac := range fracs {
+		if sintheta < 1e-12 {
+			p := Vec3{a[0] + frac*(b[0]-a[0]), a[1] + frac*(b[1]-a[1]), a[2] + frac*(b[2]-a[2])}
+			pts[i] = NVector{normalize(&p)}
+			continue
+		}
+		ca := math.Sin((1-frac)*theta) / sintheta
+		cb := math.Sin(frac*theta) / sintheta
+		pts[i] = NVector{Vec3{ca*a[0] + cb*b[0], ca*a[1] + cb*b[1], ca*a[2] + cb*b[2]}}
+	}
+	return pts
+}
+
+// InterpolateGC returns the position a fraction *frac* of the way from *p* to
+// *p2*, with the horizontal position following the great circle between
+// them, as slerp does, and the height varying linearly. The horizontal
+// n-vector of the result has unit length.
+func (p *Position) InterpolateGC(p2 *Position, frac float64) Position {
+	return Position{
+		NVector{slerp(&p.Vec3, &p2.Vec3, frac)},
+		p.Height + frac*(p2.Height-p.Height),
+	}
 }
 
+// MeanPosition returns the weighted horizontal mean of a set of positions,
+// computed by normalizing the weighted sum of their n-vectors. If *weights* is
+// nil, all positions are weighted equally. DegenerateGeometryError is
+// returned if the sum vanishes, as it does for an empty set or for positions
+// spread symmetrically about the Earth's centre.
+func MeanPosition(vs []NVector, weights []float64) (NVector, error) {
+	if weights != nil && len(weights) != len(vs) {
+		return NVector{}, LengthMismatchError{len(vs), len(weights)}
+	}
+
+	var sum Vec3
+	for i := range vs {
+		w := 1.0
+		if weights != nil {
+			w = weights[i]
+		}
+		sum[0] += w * vs[i].Vec3[0]
+		sum[1] += w * vs[i].Vec3[1]
+		sum[2] += w * vs[i].Vec3[2]
+	}
+
+	if sum.Magnitude() < 1e-12 {
+		return NVector{}, DegenerateGeometryError{"mean position is undefined"}
+	}
+	return NVector{normalize(&sum)}, nil
+}
+
+// IntersectionToleranceRad is the tolerance in radians used by Intersection
+// and SegmentIntersection to decide whether an intersection lies on a
+// segment. It is the most by which the distances from the intersection to the
+// ends of a segment may together exceed its length, about 6.4 mm on the
+// Earth, so an intersection is accepted if it overshoots the end of a segment
+// by up to half of that, about 3.2 mm.
+const IntersectionToleranceRad = 1e-9
+
 // Intersection returns the spheroidal intersection point between two geodesics
 // defined by an NVector pair, if it exists. If no intersection exists,
-// NoIntersectionError is returned
+// NoIntersectionError is returned. If the great circles coincide, or either
+// pair of points doesn't define a great circle, ParallelGreatCirclesError is
+// returned. The intersection is accepted as lying on the segments to within
+// IntersectionToleranceRad; use IntersectionTol to choose another tolerance.
 func Intersection(nv1a, nv1b, nv2a, nv2b *NVector) (NVector, error) {
-	var normalA, normalB, intersection *Vec3
+	return IntersectionTol(nv1a, nv1b, nv2a, nv2b, IntersectionToleranceRad)
+}
+
+// IntersectionTol is Intersection with the tolerance for deciding whether the
+// intersection lies on both segments given by *tolRad*, in radians. This is
+// the most by which the distances from the intersection to the ends of a
+// segment may exceed the length of the segment, so an intersection beyond the
+// end of a segment is accepted if it overshoots by up to half of *tolRad*.
+func IntersectionTol(nv1a, nv1b, nv2a, nv2b *NVector, tolRad float64) (NVector, error) {
+	var normalA, normalB, intersection Vec3
 	var err error
 
-	normalA = cross(&nv1a.Vec3, &nv1b.Vec3)
-	normalB = cross(&nv2a.Vec3, &nv2b.Vec3)
-	intersection = cross(normalA, normalB)
+	normalA = nv1a.GreatCircleNormal(nv1b)
+	normalB = nv2a.GreatCircleNormal(nv2b)
+	intersection = cross(&normalA, &normalB)
+	if intersection.Magnitude() < 1e-12 {
+		return NVector{}, ParallelGreatCirclesError{}
+	}
+	intersection = normalize(&intersection)
 
 	// Select the intersection on the right side of the spheroid
-	if dot(intersection, &nv1a.Vec3) < 0 {
+	if dot(&intersection, &nv1a.Vec3) < 0 {
 		intersection[0] = -intersection[0]
 		intersection[1] = -intersection[1]
 		intersection[2] = -intersection[2]
 	}
 
-	result := NVector{*intersection}
-
-	// Tests whether intersection is between segment endpoints to within ~4cm
-	var dab, dai, dbi float64
-	dab = nv1a.SphericalDistance(nv1b, 1.0)
-	dai = nv1a.SphericalDistance(&result, 1.0)
-	dbi = nv1b.SphericalDistance(&result, 1.0)
-
-	if math.Abs(dab-dai-dbi) > 1e-9 {
+	result := NVector{intersection}
+	if !OnArc(nv1a, nv1b, &result, tolRad) || !OnArc(nv2a, nv2b, &result, tolRad) {
 		err = NoIntersectionError{}
 	}
-
-	dab = nv2a.SphericalDistance(nv2b, 1.0)
-	dai = nv2a.SphericalDistance(&result, 1.0)
-	dbi = nv2b.SphericalDistance(&result, 1.0)
-
-	if math.Abs(dab-dai-dbi) > 1e-9 {
-		err = NoIntersectionError{}
-	}
-
-	return NVector{*intersection}, err
+	return result, err
 }
@@ -52,6 +52,33 @@ func TestNewLonLatInvalid(t *testing.T) {
 	}
 }
 
+func TestNewLonLatRadians(t *testing.T) {
+	lonlat, err := NewLonLatRadians(-2.443460952792061, 0.8595746566072073)
+	if err != nil {
+		t.Error(err)
+	}
+	if lonlat.Lon != -2.443460952792061 || lonlat.Lat != 0.8595746566072073 {
+		t.Fail()
+	}
+
+	lonlat, _ = NewLonLatRadians(1.5*math.Pi, 0)
+	if !isclose(lonlat.Lon, -0.5*math.Pi, 12) {
+		t.Fail()
+	}
+
+	if _, err := NewLonLatRadians(0, 1.6); err == nil {
+		t.Fail()
+	}
+}
+
+func TestLonLatDegrees(t *testing.T) {
+	lonlat, _ := NewLonLat(-140.0, 49.25)
+	londeg, latdeg := lonlat.Degrees()
+	if !isclose(londeg, -140, 12) || !isclose(latdeg, 49.25, 12) {
+		t.Fail()
+	}
+}
+
 func TestLonLatToNVector1(t *testing.T) {
 	ll, _ := NewLonLat(0.0, 0.0)
 	nv := ll.ToNVector()
@@ -107,18 +134,138 @@ func TestNVectorToLonLat(t *testing.T) {
 	}
 }
 
+func TestNVectorToLonLatWestern(t *testing.T) {
+	// longitudes beyond ±90 degrees are preserved
+	for _, londeg := range []float64{-180, -135, 100, 179.5} {
+		ll, _ := NewLonLat(londeg, 20)
+		nv := ll.ToNVector()
+		ll2 := nv.ToLonLat()
+		if !isclose(WrapLongitude(ll2.Lon-ll.Lon), 0, 12) {
+			t.Error(londeg, ll2.Lon*180/math.Pi)
+		}
+	}
+}
+
+func TestWrapLongitude(t *testing.T) {
+	cases := [][2]float64{
+		{0, 0},
+		{math.Pi, -math.Pi},
+		{-math.Pi, -math.Pi},
+		{1.5 * math.Pi, -0.5 * math.Pi},
+		{-1.5 * math.Pi, 0.5 * math.Pi},
+		{7 * math.Pi, -math.Pi},
+		{-5.25 * math.Pi, 0.75 * math.Pi},
+	}
+	for _, c := range cases {
+		if !isclose(WrapLongitude(c[0]), c[1], 12) {
+			t.Error(c[0], WrapLongitude(c[0]))
+		}
+	}
+	// values already in range are returned exactly
+	if WrapLongitude(0.1) != 0.1 {
+		t.Fail()
+	}
+}
+
+func TestClampLatitude(t *testing.T) {
+	if lat, err := ClampLatitude(0.3); err != nil || lat != 0.3 {
+		t.Fail()
+	}
+	if lat, err := ClampLatitude(0.5 * math.Pi); err != nil || lat != 0.5*math.Pi {
+		t.Fail()
+	}
+	lat, err := ClampLatitude(-2)
+	if lat != -0.5*math.Pi {
+		t.Fail()
+	}
+	if e, ok := err.(InvalidLatitudeError); !ok || !isclose(e.Lat, -2*180/math.Pi, 9) {
+		t.Fail()
+	}
+	if _, err := ClampLatitude(math.NaN()); err == nil {
+		t.Fail()
+	}
+}
+
+func TestToLonLatPoles(t *testing.T) {
+	for _, nv := range []NVector{{Vec3{0, 0, 1}}, {Vec3{0, 0, -1}}} {
+		ll := nv.ToLonLat()
+		if ll.Lat != math.Copysign(0.5*math.Pi, nv.Vec3[2]) || ll.Lon != 0 {
+			t.Error(ll)
+		}
+	}
+	for _, latdeg := range []float64{90, -90} {
+		ll, _ := NewLonLat(-120, latdeg)
+		nv := ll.ToNVector()
+		ll2 := nv.ToLonLat()
+		if ll2.Lat != ll.Lat {
+			t.Error(ll2.Lat)
+		}
+	}
+}
+
+func TestIsPole(t *testing.T) {
+	north := NVector{Vec3{0, 0, 1}}
+	south := NVector{Vec3{0, 0, -1}}
+	if !north.IsPole(0) || !south.IsPole(0) {
+		t.Fail()
+	}
+	ll, _ := NewLonLat(30, 89.999)
+	near := ll.ToNVector()
+	if near.IsPole(1e-6) || !near.IsPole(1e-4) {
+		t.Fail()
+	}
+	ll, _ = NewLonLat(30, 0)
+	equator := ll.ToNVector()
+	if equator.IsPole(0.1) {
+		t.Fail()
+	}
+}
+
+func TestToECEFSphere(t *testing.T) {
+	R := 6371e3
+	ll, _ := NewLonLat(90, 0)
+	nv := ll.ToNVector()
+	v := nv.ToECEFSphere(R)
+	if !isclose(v[0], 0, 6) || !isclose(v[1], R, 6) || !isclose(v[2], 0, 6) {
+		t.Error(v)
+	}
+
+	ll, _ = NewLonLat(-33.2, 71.4)
+	nv = ll.ToNVector()
+	v = nv.ToECEFSphere(R)
+	if !isclose(v.Magnitude(), R, 6) {
+		t.Fail()
+	}
+	back := NVectorFromECEFSphere(v)
+	if !isclose(back.AngleTo(&nv), 0, 12) || !back.IsUnit(1e-12) {
+		t.Fail()
+	}
+}
+
+func TestIsUnit(t *testing.T) {
+	ll, _ := NewLonLat(-140, 49.25)
+	nv := ll.ToNVector()
+	if !nv.IsUnit(1e-12) {
+		t.Fail()
+	}
+	scaled := NVector{Vec3{2 * nv.Vec3[0], 2 * nv.Vec3[1], 2 * nv.Vec3[2]}}
+	if scaled.IsUnit(1e-6) || !scaled.IsUnit(1) {
+		t.Fail()
+	}
+}
+
 func Test_cross(t *testing.T) {
 	v1 := Vec3{1, 0, 0}
 	v2 := Vec3{0, 1, 0}
 	v3 := cross(&v1, &v2)
-	if (*v3 != Vec3{0, 0, 1}) {
+	if (v3 != Vec3{0, 0, 1}) {
 		t.Fail()
 	}
 
 	v4 := Vec3{0, 1, 0}
 	v5 := Vec3{1, 0, 0}
 	v6 := cross(&v4, &v5)
-	if (*v6 != Vec3{0, 0, -1}) {
+	if (v6 != Vec3{0, 0, -1}) {
 		t.Fail()
 	}
 }
@@ -339,6 +486,131 @@ func TestInterpolate(t *testing.T) {
 	}
 }
 
+func TestIntersectionParallel(t *testing.T) {
+	ll1, _ := NewLonLat(-50, 0)
+	ll2, _ := NewLonLat(-30, 0)
+	ll3, _ := NewLonLat(-20, 0)
+	ll4, _ := NewLonLat(10, 0)
+	nv1, nv2, nv3, nv4 := ll1.ToNVector(), ll2.ToNVector(), ll3.ToNVector(), ll4.ToNVector()
+
+	// both segments lie on the equator
+	_, err := Intersection(&nv1, &nv2, &nv3, &nv4)
+	if _, ok := err.(ParallelGreatCirclesError); !ok {
+		t.Error(err)
+	}
+
+	// a repeated point doesn't define a great circle
+	_, err = Intersection(&nv1, &nv1, &nv3, &nv4)
+	if _, ok := err.(ParallelGreatCirclesError); !ok {
+		t.Error(err)
+	}
+}
+
+func TestIntersectionTol(t *testing.T) {
+	R := 6371e3
+	ll1, _ := NewLonLat(-50, 0)
+	ll2, _ := NewLonLat(-30, 0)
+	nv1, nv2 := ll1.ToNVector(), ll2.ToNVector()
+
+	// a meridian segment ending 1 m short of the equator
+	start := nv2.Forward(0, 1000, R)
+	end := nv2.Forward(0, 1, R)
+	if _, err := Intersection(&nv1, &nv2, &start, &end); err == nil {
+		t.Error("expected NoIntersectionError")
+	}
+	if _, err := IntersectionTol(&nv1, &nv2, &start, &end, 3/R); err != nil {
+		t.Error(err)
+	}
+	if _, err := IntersectionTol(&nv1, &nv2, &start, &end, 1.5/R); err == nil {
+		t.Fail()
+	}
+}
+
+func TestIntersectionTolerance(t *testing.T) {
+	R := EarthRadiusMean
+	ll1, _ := NewLonLat(-50, 0)
+	ll2, _ := NewLonLat(-30, 0)
+	nv1, nv2 := ll1.ToNVector(), ll2.ToNVector()
+
+	// meridian segments ending just short of the equator are accepted within
+	// half the tolerance, about 3.2 mm
+	for _, short := range []float64{0.002, 0.003, 0.0034, 0.005} {
+		start := nv2.Forward(0, 1000, R)
+		end := nv2.Forward(0, short, R)
+		_, err := Intersection(&nv1, &nv2, &start, &end)
+		if accepted := short < 0.5*IntersectionToleranceRad*R; (err == nil) != accepted {
+			t.Error(short, err)
+		}
+	}
+}
+
+func TestInterpolateValue(t *testing.T) {
+	nv1 := NVector{Vec3{0, 3, 2}}
+	nv2 := NVector{Vec3{-7, 5, -3}}
+	if nv1.InterpolateValue(nv2, 0.2) != nv1.Interpolate(&nv2, 0.2) {
+		t.Fail()
+	}
+	allocs := testing.AllocsPerRun(100, func() {
+		nv1 = nv1.InterpolateValue(nv2, 0.2)
+	})
+	if allocs != 0 {
+		t.Error(allocs)
+	}
+}
+
+func TestInterpolateMany(t *testing.T) {
+	ll1, _ := NewLonLat(-10, 0)
+	ll2, _ := NewLonLat(30, 0)
+	nv1 := ll1.ToNVector()
+	nv2 := ll2.ToNVector()
+	pts := nv1.InterpolateMany(&nv2, []float64{0, 0.25, 1, -0.5, 1.5})
+	expected := []float64{-10, 0, 30, -30, 50}
+	for i, pt := range pts {
+		ll := pt.ToLonLat()
+		if !isclose(ll.Lon*180/math.Pi, expected[i], 9) || !isclose(ll.Lat, 0, 12) {
+			t.Error(i, ll)
+		}
+		if !pt.IsUnit(1e-12) {
+			t.Error(i)
+		}
+	}
+
+	// fractions map to proportional angles along the arc
+	ll3, _ := NewLonLat(60, 45)
+	nv3 := ll3.ToNVector()
+	for i, pt := range nv1.InterpolateMany(&nv3, []float64{0.1, 0.5, 2}) {
+		d := nv1.AngleTo(&pt) / nv1.AngleTo(&nv3)
+		if !isclose(d, []float64{0.1, 0.5, 2}[i], 9) || !pt.IsUnit(1e-12) {
+			t.Error(i, d)
+		}
+	}
+
+	if len(nv1.InterpolateMany(&nv2, nil)) != 0 {
+		t.Fail()
+	}
+}
+
+func TestPositionInterpolateGC(t *testing.T) {
+	ll1, _ := NewLonLat(-10, 0)
+	ll2, _ := NewLonLat(30, 0)
+	p1 := Position{ll1.ToNVector(), 1000}
+	p2 := Position{ll2.ToNVector(), 11000}
+
+	mid := p1.InterpolateGC(&p2, 0.5)
+	ll := mid.ToLonLat()
+	if !isclose(ll.Lon*180/math.Pi, 10, 9) || !isclose(ll.Lat, 0, 12) || !isclose(mid.Height, 6000, 9) {
+		t.Error(ll, mid.Height)
+	}
+	if !mid.IsUnit(1e-12) {
+		t.Fail()
+	}
+
+	end := p1.InterpolateGC(&p2, 1)
+	if !isclose(end.AngleTo(&p2.NVector), 0, 12) || !isclose(end.Height, 11000, 9) {
+		t.Fail()
+	}
+}
+
 func TestIntersection1(t *testing.T) {
 	ll1, _ := NewLonLat(-50, 0)
 	ll2, _ := NewLonLat(-30, 0)
@@ -384,3 +656,519 @@ func TestIntersection2(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestPVectorRoundTrip(t *testing.T) {
+	ll, _ := NewLonLat(30, 40)
+	nv := ll.ToNVector()
+	ellps := Ellipsoid{6378137.0, 6356752.3142}
+	pv := nv.ToPVector(&ellps)
+	nv2 := pv.ToNVector(&ellps)
+	for i := 0; i < 3; i++ {
+		if !isclose(nv.Vec3[i], nv2.Vec3[i], 12) {
+			t.Fail()
+		}
+	}
+}
+
+func TestPVectorRoundTripOctants(t *testing.T) {
+	ellps := Ellipsoid{6378137.0, 6356752.3142}
+	cases := []struct {
+		name     string
+		lon, lat float64
+	}{
+		{"NE, 0-90E", 45, 30},
+		{"NE, 90-180E", 135, 60},
+		{"NW, 0-90W", -45, 10},
+		{"NW, 90-180W", -135, 80},
+		{"SE, 0-90E", 20, -30},
+		{"SE, 90-180E", 170, -60},
+		{"SW, 0-90W", -80, -5},
+		{"SW, 90-180W", -100, -85},
+	}
+	for _, c := range cases {
+		ll, _ := NewLonLat(c.lon, c.lat)
+		nv := ll.ToNVector()
+		for _, height := range []float64{0, 1000, 400e3} {
+			surface := nv.ToPVector(&ellps)
+			pv := PVector{Vec3{surface.Vec3[0] + height*nv.Vec3[0],
+				surface.Vec3[1] + height*nv.Vec3[1],
+				surface.Vec3[2] + height*nv.Vec3[2]}}
+			nv2 := pv.ToNVector(&ellps)
+			pv2 := nv2.ToPVector(&ellps)
+			d := Vec3{pv2.Vec3[0] - surface.Vec3[0], pv2.Vec3[1] - surface.Vec3[1], pv2.Vec3[2] - surface.Vec3[2]}
+			if d.Magnitude() > 0.001 {
+				t.Errorf("%s at height %f: round trip off by %f m", c.name, height, d.Magnitude())
+			}
+		}
+	}
+}
+
+func TestToENU(t *testing.T) {
+	ellps := Ellipsoid{6378137.0, 6356752.3142}
+	ll1, _ := NewLonLat(10, 50)
+	ll2, _ := NewLonLat(10, 50.1)
+	ll3, _ := NewLonLat(10.1, 50)
+	nv1 := ll1.ToNVector()
+	nv2 := ll2.ToNVector()
+	nv3 := ll3.ToNVector()
+
+	// due north
+	enu := nv1.ToENU(&nv2, &ellps)
+	if !isclose(enu[0], 0, 6) || enu[1] < 11000 || enu[1] > 11200 || enu[2] >= 0 {
+		t.Fail()
+	}
+
+	// roughly due east, dropping below the tangent plane
+	enu = nv1.ToENU(&nv3, &ellps)
+	if enu[0] < 7000 || enu[0] > 7300 || enu[1] <= 0 || enu[2] >= 0 {
+		t.Fail()
+	}
+}
+
+func TestENURoundTrip(t *testing.T) {
+	ellps := Ellipsoid{6378137.0, 6356752.3142}
+	origin, _ := NewLonLat(-123.1, 49.3)
+	target, _ := NewLonLat(-122.8, 49.5)
+	nv1 := origin.ToNVector()
+	nv2 := target.ToNVector()
+
+	enu := nv1.ToENU(&nv2, &ellps)
+	nv3 := nv1.FromENU(enu, &ellps)
+	if nv2.SphericalDistance(&nv3, 6371000) > 1e-6 {
+		t.Fail()
+	}
+}
+
+func TestDeltaNED(t *testing.T) {
+	ellps := Ellipsoid{6378137.0, 6356752.3142}
+	ll1, _ := NewLonLat(0, 0)
+	ll2, _ := NewLonLat(0, 1)
+	nv1 := ll1.ToNVector()
+	nv2 := ll2.ToNVector()
+
+	delta := nv1.DeltaNED(&nv2, &ellps)
+	if delta[0] < 110000 || delta[0] > 111000 {
+		t.Fail()
+	}
+	if !isclose(delta[1], 0, 6) {
+		t.Fail()
+	}
+	// the chord dips below the tangent plane
+	if delta[2] < 900 || delta[2] > 1000 {
+		t.Fail()
+	}
+
+	// consistent with the azimuth
+	ll3, _ := NewLonLat(-143, 49.25)
+	ll4, _ := NewLonLat(-140, 49.25)
+	nv3 := ll3.ToNVector()
+	nv4 := ll4.ToNVector()
+	delta = nv4.DeltaNED(&nv3, &ellps)
+	if !isclose(math.Atan2(delta[1], delta[0]), nv4.Azimuth(&nv3, &ellps), 12) {
+		t.Fail()
+	}
+}
+
+func TestMeanPosition(t *testing.T) {
+	// symmetric about the dateline, where averaging longitudes fails
+	ll1, _ := NewLonLat(170, 10)
+	ll2, _ := NewLonLat(-170, 10)
+	vs := []NVector{ll1.ToNVector(), ll2.ToNVector()}
+
+	mean, err := MeanPosition(vs, nil)
+	if err != nil {
+		t.Error(err)
+	}
+	d1 := mean.SphericalDistance(&vs[0], 1.0)
+	d2 := mean.SphericalDistance(&vs[1], 1.0)
+	if !isclose(d1, d2, 12) || !isclose(d1+d2, vs[0].SphericalDistance(&vs[1], 1.0), 12) {
+		t.Fail()
+	}
+	if !isclose(mean.Vec3[1], 0, 12) || mean.Vec3[0] > 0 {
+		t.Fail()
+	}
+}
+
+func TestMeanPositionWeighted(t *testing.T) {
+	ll1, _ := NewLonLat(0, 0)
+	ll2, _ := NewLonLat(0, 10)
+	vs := []NVector{ll1.ToNVector(), ll2.ToNVector()}
+
+	mean, err := MeanPosition(vs, []float64{1, 0})
+	if err != nil {
+		t.Error(err)
+	}
+	if !isclose(mean.SphericalDistance(&vs[0], 1.0), 0, 12) {
+		t.Fail()
+	}
+
+	mean, _ = MeanPosition(vs, []float64{1, 3})
+	if mean.SphericalDistance(&vs[1], 1.0) >= mean.SphericalDistance(&vs[0], 1.0) {
+		t.Fail()
+	}
+
+	if _, err = MeanPosition(vs, []float64{1}); err == nil {
+		t.Fail()
+	}
+}
+
+func TestMeanPositionAntipodal(t *testing.T) {
+	ll1, _ := NewLonLat(30, 20)
+	ll2, _ := NewLonLat(-150, -20)
+	vs := []NVector{ll1.ToNVector(), ll2.ToNVector()}
+	_, err := MeanPosition(vs, nil)
+	if _, ok := err.(DegenerateGeometryError); !ok {
+		t.Fail()
+	}
+}
+
+func TestForward3(t *testing.T) {
+	// away from the equator, the distance travelled is still the distance
+	// requested
+	pos, _ := NewLonLat(-123, 60)
+	nv := pos.ToNVector()
+	R := 6370997.0
+
+	for _, az := range []float64{0, 0.5, 1.5, 3, -2} {
+		nv2 := nv.Forward(az, 100000, R)
+		if !isclose(nv.SphericalDistance(&nv2, R), 100000, 6) {
+			t.Error(az)
+		}
+		if !isclose(nv2.Magnitude(), 1, 12) {
+			t.Fail()
+		}
+		if !isclose(math.Atan2(math.Sin(bearing(&nv.Vec3, &nv2.Vec3)-az), math.Cos(bearing(&nv.Vec3, &nv2.Vec3)-az)), 0, 10) {
+			t.Fail()
+		}
+	}
+}
+
+func TestOffsetPerpendicular(t *testing.T) {
+	pos, _ := NewLonLat(10, 0)
+	nv := pos.ToNVector()
+	R := 6370997.0
+
+	// heading north, to the right is east
+	right := nv.OffsetPerpendicular(0, 1000, R)
+	left := nv.OffsetPerpendicular(0, -1000, R)
+	llRight, llLeft := right.ToLonLat(), left.ToLonLat()
+	if !(llRight.Lon > pos.Lon) || !(llLeft.Lon < pos.Lon) {
+		t.Fail()
+	}
+	if !isclose(llRight.Lat, 0, 12) || !isclose(nv.SphericalDistance(&right, R), 1000, 6) {
+		t.Fail()
+	}
+}
+
+func TestForwardChecked(t *testing.T) {
+	pos, _ := NewLonLat(-123, 60)
+	nv := pos.ToNVector()
+	R := 6370997.0
+
+	nv2, err := nv.ForwardChecked(0.5, 100000, R)
+	if err != nil {
+		t.Error(err)
+	}
+	if nv2 != nv.Forward(0.5, 100000, R) {
+		t.Fail()
+	}
+
+	for _, radius := range []float64{0, -R, math.NaN()} {
+		if _, err := nv.ForwardChecked(0.5, 100000, radius); err == nil {
+			t.Errorf("expected error for radius %f", radius)
+		}
+	}
+}
+
+func TestSphericalDistanceDefault(t *testing.T) {
+	pos1, _ := NewLonLat(-140, 49.25)
+	pos2, _ := NewLonLat(-140, 48.25)
+	nv1 := pos1.ToNVector()
+	nv2 := pos2.ToNVector()
+
+	if !isclose(nv1.SphericalDistanceDefault(&nv2), nv1.SphericalDistance(&nv2, EarthRadiusMean), 8) {
+		t.Fail()
+	}
+	if !isclose(nv1.SphericalDistanceDefault(&nv2), 111195.080, 2) {
+		t.Fail()
+	}
+}
+
+func TestAngleTo(t *testing.T) {
+	pos1, _ := NewLonLat(30, 20)
+	pos2, _ := NewLonLat(-150, -20)
+	pos3, _ := NewLonLat(30, 25)
+	nv1 := pos1.ToNVector()
+	nv2 := pos2.ToNVector()
+	nv3 := pos3.ToNVector()
+
+	if nv1.AngleTo(&nv1) != 0 {
+		t.Fail()
+	}
+	if !isclose(nv1.AngleTo(&nv2), math.Pi, 12) {
+		t.Fail()
+	}
+	if !isclose(nv1.AngleTo(&nv3), 5*math.Pi/180, 12) {
+		t.Fail()
+	}
+}
+
+func TestSmallDistance(t *testing.T) {
+	R := 6371000.0
+
+	// exactly 1 mm along the equator
+	theta := 1e-3 / R
+	nv1 := NVector{Vec3{1, 0, 0}}
+	nv2 := NVector{Vec3{math.Cos(theta), math.Sin(theta), 0}}
+	if !isclose(nv1.SmallDistance(&nv2, R), 1e-3, 12) {
+		t.Fail()
+	}
+	if !isclose(nv1.SphericalDistance(&nv2, R), 1e-3, 12) {
+		t.Fail()
+	}
+
+	// 1 mm in an oblique direction at mid-latitudes
+	pos, _ := NewLonLat(-123.1, 49.3)
+	nv3 := pos.ToNVector()
+	nv4 := nv3.Forward(0.7, 1e-3, R)
+	if !isclose(nv3.SmallDistance(&nv4, R), 1e-3, 8) {
+		t.Fail()
+	}
+
+	// agrees with SphericalDistance at larger separations
+	pos2, _ := NewLonLat(-0.1, 51.5)
+	nv5 := pos2.ToNVector()
+	if !isclose(nv3.SmallDistance(&nv5, R), nv3.SphericalDistance(&nv5, R), 6) {
+		t.Fail()
+	}
+}
+
+func TestInitialBearing(t *testing.T) {
+	origin, _ := NewLonLat(10, 0)
+	nv := origin.ToNVector()
+	cases := []struct {
+		lon, lat, expected float64
+	}{
+		{10, 5, 0},
+		{15, 0, 0.5 * math.Pi},
+		{10, -5, math.Pi},
+		{5, 0, -0.5 * math.Pi},
+	}
+	for _, c := range cases {
+		ll, _ := NewLonLat(c.lon, c.lat)
+		nv2 := ll.ToNVector()
+		if b := nv.InitialBearing(&nv2); !isclose(b, c.expected, 12) {
+			t.Error(c.lon, c.lat, b)
+		}
+	}
+
+	nv2 := nv.Forward(-2.5, 1000e3, 6371e3)
+	if !isclose(nv.InitialBearing(&nv2), -2.5, 10) {
+		t.Fail()
+	}
+}
+
+func TestBearingsTo(t *testing.T) {
+	origin, _ := NewLonLat(10, 0)
+	nv := origin.ToNVector()
+	targets := lonLatRing([][2]float64{{10, 5}, {15, 0}, {40, -40}})
+	bearings := nv.BearingsTo(targets)
+	if len(bearings) != len(targets) {
+		t.Fatal(bearings)
+	}
+	for i := range targets {
+		if bearings[i] != nv.InitialBearing(&targets[i]) {
+			t.Error(i)
+		}
+	}
+	if bearings := nv.BearingsTo(nil); bearings == nil || len(bearings) != 0 {
+		t.Fail()
+	}
+}
+
+func TestInverseSpherical(t *testing.T) {
+	R := 6371e3
+	ll1, _ := NewLonLat(0, 0)
+	ll2, _ := NewLonLat(90, 0)
+	nv1, nv2 := ll1.ToNVector(), ll2.ToNVector()
+	dist, fwd, back := nv1.InverseSpherical(&nv2, R)
+	if !isclose(dist, 0.5*math.Pi*R, 4) || !isclose(fwd, 0.5*math.Pi, 12) || !isclose(back, -0.5*math.Pi, 12) {
+		t.Error(dist, fwd, back)
+	}
+
+	pos, _ := NewLonLat(-123, 49)
+	nv := pos.ToNVector()
+	nv3 := nv.Forward(2.2, 800e3, R)
+	dist, fwd, _ = nv.InverseSpherical(&nv3, R)
+	if !isclose(dist, 800e3, 4) || !isclose(fwd, 2.2, 10) {
+		t.Error(dist, fwd)
+	}
+
+	dist, fwd, back = nv.InverseSpherical(&nv, R)
+	if dist != 0 || fwd != 0 || back != 0 {
+		t.Fail()
+	}
+
+	// the bearings agree with those found from the local frame at each end,
+	// including at the poles
+	pts := lonLatRing([][2]float64{{-123, 49}, {140, 36}, {10, -80}, {60, -45}, {0, 90}, {-30, -90}})
+	for i := range pts {
+		for j := range pts {
+			if i == j {
+				continue
+			}
+			_, fwd, back := pts[i].InverseSpherical(&pts[j], R)
+			if !isclose(wrapAngle(fwd-bearing(&pts[i].Vec3, &pts[j].Vec3)), 0, 10) ||
+				!isclose(wrapAngle(back-bearing(&pts[j].Vec3, &pts[i].Vec3)), 0, 10) {
+				t.Error(i, j, fwd, back)
+			}
+		}
+	}
+}
+
+func TestSphericalDistanceLawCosines(t *testing.T) {
+	pos, _ := NewLonLat(30, 60)
+	nv := pos.ToNVector()
+	R := 6371e3
+	nv2 := nv.Forward(0.8, 500e3, R)
+	if !isclose(nv.SphericalDistanceLawCosines(&nv2, R), nv.SphericalDistance(&nv2, R), 4) {
+		t.Fail()
+	}
+
+	// at short range the law of cosines loses precision while the atan2 form
+	// does not
+	nv3 := nv.Forward(0.8, 0.05, R)
+	if !isclose(nv.SphericalDistance(&nv3, R), 0.05, 6) {
+		t.Fail()
+	}
+	if math.Abs(nv.SphericalDistanceLawCosines(&nv3, R)-0.05) < 0.01 {
+		t.Error("expected law of cosines to lose precision at 5 cm")
+	}
+}
+
+func TestHaversineDistance(t *testing.T) {
+	R := 6371e3
+	for _, c := range [][4]float64{{-140, 49.25, -120, 30}, {0, 0, 179, 1}, {10, 10, 10.0001, 10}} {
+		ll1, _ := NewLonLat(c[0], c[1])
+		ll2, _ := NewLonLat(c[2], c[3])
+		nv1, nv2 := ll1.ToNVector(), ll2.ToNVector()
+		if !isclose(HaversineDistance(*ll1, *ll2, R), nv1.SphericalDistance(&nv2, R), 5) {
+			t.Error(c)
+		}
+	}
+}
+
+func TestWithinDistance(t *testing.T) {
+	pos, _ := NewLonLat(-140, 49.25)
+	nv := pos.ToNVector()
+	R := 6370997.0
+	nv2 := nv.Forward(1.2, 10000, R)
+
+	if !nv.WithinDistance(&nv2, 10000.01, R) {
+		t.Fail()
+	}
+	if nv.WithinDistance(&nv2, 9999.99, R) {
+		t.Fail()
+	}
+	if !nv.WithinDistance(&nv, 0, R) {
+		t.Fail()
+	}
+	if nv.WithinDistance(&nv, -1, R) || nv.WithinDistance(&nv2, -10000.01, R) {
+		t.Error("negative distances should exclude every position")
+	}
+
+	// centimetre thresholds remain reliable
+	nv3 := nv.Forward(1.2, 0.02, R)
+	if !nv.WithinDistance(&nv3, 0.021, R) || nv.WithinDistance(&nv3, 0.019, R) {
+		t.Fail()
+	}
+
+	antipode := NVector{Vec3{-nv.Vec3[0], -nv.Vec3[1], -nv.Vec3[2]}}
+	if !nv.WithinDistance(&antipode, 4*R, R) {
+		t.Fail()
+	}
+}
+
+func TestWithinRadius(t *testing.T) {
+	pos, _ := NewLonLat(2.35, 48.85)
+	center := pos.ToNVector()
+	R := 6371e3
+	near := center.Forward(0.5, 999, R)
+	far := center.Forward(0.5, 1001, R)
+	if !near.WithinRadius(&center, 1000, R) {
+		t.Fail()
+	}
+	if far.WithinRadius(&center, 1000, R) {
+		t.Fail()
+	}
+	if !center.WithinRadius(&center, 0, R) {
+		t.Error("boundary should be inclusive")
+	}
+}
+
+func BenchmarkWithinDistance(b *testing.B) {
+	pos1, _ := NewLonLat(174, -15)
+	pos2, _ := NewLonLat(-177.5, 36)
+	nv1 := pos1.ToNVector()
+	nv2 := pos2.ToNVector()
+	for i := 0; i < b.N; i++ {
+		nv1.WithinDistance(&nv2, 6000000, 6370997.0)
+	}
+}
+
+func BenchmarkSphericalDistanceThreshold(b *testing.B) {
+	pos1, _ := NewLonLat(174, -15)
+	pos2, _ := NewLonLat(-177.5, 36)
+	nv1 := pos1.ToNVector()
+	nv2 := pos2.ToNVector()
+	for i := 0; i < b.N; i++ {
+		_ = nv1.SphericalDistance(&nv2, 6370997.0) <= 6000000
+	}
+}
+
+func BenchmarkInterpolateValue(b *testing.B) {
+	pos1, _ := NewLonLat(174, -15)
+	pos2, _ := NewLonLat(-177.5, 36)
+	nv1 := pos1.ToNVector()
+	nv2 := pos2.ToNVector()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		nv1.InterpolateValue(nv2, 0.3)
+	}
+}
+
+func TestHotPathAllocations(t *testing.T) {
+	pos1, _ := NewLonLat(174, -15)
+	pos2, _ := NewLonLat(-177.5, 36)
+	nv1 := pos1.ToNVector()
+	nv2 := pos2.ToNVector()
+
+	// segments that cross, so that Intersection runs to completion
+	crossing := lonLatRing([][2]float64{{-50, 0}, {-30, 0}, {-40, -5}, {-40, 3}})
+	if _, err := Intersection(&crossing[0], &crossing[1], &crossing[2], &crossing[3]); err != nil {
+		t.Fatal(err)
+	}
+
+	funcs := map[string]func(){
+		"SphericalDistance": func() { nv1.SphericalDistance(&nv2, 6370997.0) },
+		"Forward":           func() { nv1.Forward(1.2, 10000, 6370997.0) },
+		"Intersection":      func() { Intersection(&crossing[0], &crossing[1], &crossing[2], &crossing[3]) },
+		"RotationMatrix":    func() { nv1.RotationMatrix() },
+	}
+	for name, f := range funcs {
+		if allocs := testing.AllocsPerRun(100, f); allocs != 0 {
+			t.Errorf("%s: %f allocations per call", name, allocs)
+		}
+	}
+}
+
+func BenchmarkSphericalDistance(b *testing.B) {
+	pos1, _ := NewLonLat(174, -15)
+	pos2, _ := NewLonLat(-177.5, 36)
+	nv1 := pos1.ToNVector()
+	nv2 := pos2.ToNVector()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		nv1.SphericalDistance(&nv2, 6370997.0)
+	}
+}
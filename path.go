@@ -0,0 +1,417 @@
+package nvector
+
+import "math"
+
+// PathLength returns the length of the path through *pts* on a sphere with
+// radius *R*. Paths with fewer than two points have zero length.
+func PathLength(pts []NVector, R float64) float64 {
+	var length float64
+	for i := 1; i < len(pts); i++ {
+		length += pts[i-1].SphericalDistance(&pts[i], R)
+	}
+	return length
+}
+
+// PathLengthEllipsoid returns the length of the path through *pts*, following
+// geodesics on an ellipsoid computed with Vincenty's inverse formula. An error
+// is returned if any segment fails to converge.
+func PathLengthEllipsoid(pts []NVector, ellps *Ellipsoid) (float64, error) {
+	var length float64
+	for i := 1; i < len(pts); i++ {
+		d, _, _, err := vincentyInverse(&pts[i-1], &pts[i], ellps)
+		if err != nil {
+			return math.NaN(), err
+		}
+		length += d
+	}
+	return length, nil
+}
+
+// PathLengthPrecise returns the length of the path through *points* on a
+// sphere with radius *R*, accumulating the segment lengths with Kahan
+// compensated summation. For paths with very many vertices this avoids the
+// drift that builds up when the lengths are summed naively.
+func PathLengthPrecise(points []NVector, R float64) float64 {
+	var sum, compensation float64
+	for i := 1; i < len(points); i++ {
+		y := points[i-1].SphericalDistance(&points[i], R) - compensation
+		t := sum + y
+		compensation = (t - sum) - y
+		sum = t
+	}
+	return sum
+}
+
+// TurnAngle returns the change of course, in radians, made at waypoint *at* by
+// a route arriving along the great circle from *prev* and leaving along the
+// great circle toward *next*. Positive values are turns to the right
+// (clockwise) and negative values turns to the left, in the range [-pi, pi).
+func TurnAngle(prev, at, next *NVector) float64 {
+	arriving := bearing(&at.Vec3, &prev.Vec3) + math.Pi
+	leaving := bearing(&at.Vec3, &next.Vec3)
+	return wrapAngle(leaving - arriving)
+}
+
+// TrackSimilarity returns the discrete Fréchet distance between tracks *a* and
+// *b* on a sphere with radius *R*: the smallest, over all monotone couplings
+// of the two vertex sequences, of the largest great circle distance between
+// coupled vertices. Identical tracks give zero. If either track is empty, the
+// result is +Inf.
+func TrackSimilarity(a, b []NVector, R float64) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return math.Inf(1)
+	}
+
+	// dynamic programme over the coupling table, one row at a time
+	prev := make([]float64, len(b))
+	curr := make([]float64, len(b))
+	for i := range a {
+		for j := range b {
+			d := a[i].SphericalDistance(&b[j], R)
+			switch {
+			case i == 0 && j == 0:
+				curr[j] = d
+			case i == 0:
+				curr[j] = math.Max(curr[j-1], d)
+			case j == 0:
+				curr[j] = math.Max(prev[j], d)
+			default:
+				reach := math.Min(math.Min(prev[j], prev[j-1]), curr[j-1])
+				curr[j] = math.Max(reach, d)
+			}
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)-1]
+}
+
+// GreatCirclePoints returns *n* points equally spaced along the great circle
+// from *a* to *b*, including both endpoints. If *n* is 1 only *a* is
+// returned, and if *n* is less than 1 the result is empty.
+func GreatCirclePoints(a, b *NVector, n int) []NVector {
+	if n < 1 {
+		return nil
+	}
+	if n == 1 {
+		return []NVector{*a}
+	}
+	pts := make([]NVector, n)
+	for i := 0; i < n; i++ {
+		pts[i] = NVector{slerp(&a.Vec3, &b.Vec3, float64(i)/float64(n-1))}
+	}
+	return pts
+}
+
+// Densify returns a copy of the path through *pts* with points inserted along
+// the great circle between any two vertices more than *maxSeg* apart on a
+// sphere with radius *R*, so that no segment is longer than *maxSeg*. The
+// original vertices are all retained. If *maxSeg* is not positive the path is
+// returned unchanged.
+func Densify(pts []NVector, maxSeg float64, R float64) []NVector {
+	if len(pts) == 0 {
+		return nil
+	}
+	dense := []NVector{pts[0]}
+	for i := 1; i < len(pts); i++ {
+		if maxSeg > 0 {
+			d := pts[i-1].SphericalDistance(&pts[i], R)
+			n := int(math.Ceil(d / maxSeg))
+			for j := 1; j < n; j++ {
+				dense = append(dense, NVector{slerp(&pts[i-1].Vec3, &pts[i].Vec3, float64(j)/float64(n))})
+			}
+		}
+		dense = append(dense, pts[i])
+	}
+	return dense
+}
+
+// ResampleByDistance returns positions spaced every *spacing* along the great
+// circle path through *pts*, measured on a sphere with radius *R*. The first
+// position is the start of the path. The end of the path is always included,
+// so the final interval may be shorter than *spacing*. If *spacing* is not
+// positive the path is returned unchanged.
+func ResampleByDistance(pts []NVector, spacing float64, R float64) []NVector {
+	if len(pts) == 0 || !(spacing > 0) {
+		return append([]NVector(nil), pts...)
+	}
+	resampled := []NVector{pts[0]}
+	next := spacing
+	travelled := 0.0
+	for i := 1; i < len(pts); i++ {
+		d := pts[i-1].SphericalDistance(&pts[i], R)
+		for d > 0 && next <= travelled+d {
+			frac := (next - travelled) / d
+			resampled = append(resampled, NVector{slerp(&pts[i-1].Vec3, &pts[i].Vec3, frac)})
+			next += spacing
+		}
+		travelled += d
+	}
+	if travelled-(next-spacing) > 1e-9*spacing {
+		resampled = append(resampled, pts[len(pts)-1])
+	}
+	return resampled
+}
+
+// ClosestOnSegment returns the point on the great circle arc from *a* to *b*
+// nearest to *pt*. This is the foot of the perpendicular from *pt* to the
+// great circle if it falls within the arc, and otherwise the nearer endpoint.
+func ClosestOnSegment(pt, a, b *NVector) NVector {
+	n := a.GreatCircleNormal(b)
+	if n == (Vec3{}) {
+		return *a
+	}
+
+	// foot of the perpendicular from pt to the great circle
+	p := normalize(&pt.Vec3)
+	pn := dot(&p, &n)
+	foot := Vec3{p[0] - pn*n[0], p[1] - pn*n[1], p[2] - pn*n[2]}
+	foot = normalize(&foot)
+	afoot := cross(&a.Vec3, &foot)
+	footb := cross(&foot, &b.Vec3)
+	if foot != (Vec3{}) && dot(&afoot, &n) >= 0 && dot(&footb, &n) >= 0 {
+		return NVector{foot}
+	}
+
+	if pt.SphericalDistance(a, 1.0) <= pt.SphericalDistance(b, 1.0) {
+		return *a
+	}
+	return *b
+}
+
+// DistanceToPolyline returns the shortest distance on a sphere with radius *R*
+// from *pt* to the great circle path through *line*. A line of a single
+// vertex gives the distance to that vertex, and an empty line gives +Inf.
+func DistanceToPolyline(pt *NVector, line []NVector, R float64) float64 {
+	switch len(line) {
+	case 0:
+		return math.Inf(1)
+	case 1:
+		return pt.SphericalDistance(&line[0], R)
+	}
+	dist := math.Inf(1)
+	for i := 1; i < len(line); i++ {
+		closest := ClosestOnSegment(pt, &line[i-1], &line[i])
+		dist = math.Min(dist, pt.SphericalDistance(&closest, R))
+	}
+	return dist
+}
+
+// MapMatch returns, for each position in *track*, the nearest point on the
+// great circle paths in *network*, with distances measured on a sphere with
+// radius *R*. Each position is matched independently and greedily to the
+// nearest segment, without regard to the connectivity of the network or to
+// the matches of its neighbours along the track. A polyline of a single
+// vertex matches only that vertex, and a network without vertices gives nil.
+func MapMatch(track []NVector, network [][]NVector, R float64) []NVector {
+	var matched []NVector
+	for i := range track {
+		pt := &track[i]
+		var best NVector
+		dist := math.Inf(1)
+		for _, line := range network {
+			for j := range line {
+				var closest NVector
+				switch {
+				case len(line) == 1:
+					closest = line[0]
+				case j == 0:
+					continue
+				default:
+					closest = ClosestOnSegment(pt, &line[j-1], &line[j])
+				}
+				if d := pt.SphericalDistance(&closest, R); d < dist {
+					best, dist = closest, d
+				}
+			}
+		}
+		if math.IsInf(dist, 1) {
+			return nil
+		}
+		matched = append(matched, best)
+	}
+	return matched
+}
+
+// Simplify returns a simplified copy of the path through *pts* using the
+// Ramer-Douglas-Peucker algorithm, measuring the distance from each vertex to
+// the great circle arcs of the simplified path on a sphere with radius *R*.
+// Every discarded vertex lies within *tolerance* of the simplified path, and
+// the endpoints are always retained.
+func Simplify(pts []NVector, tolerance float64, R float64) []NVector {
+	if len(pts) < 3 {
+		return append([]NVector(nil), pts...)
+	}
+
+	keep := make([]bool, len(pts))
+	keep[0] = true
+	keep[len(pts)-1] = true
+
+	stack := [][2]int{{0, len(pts) - 1}}
+	for len(stack) > 0 {
+		first, last := stack[len(stack)-1][0], stack[len(stack)-1][1]
+		stack = stack[:len(stack)-1]
+
+		index := -1
+		farthest := tolerance
+		for i := first + 1; i < last; i++ {
+			closest := ClosestOnSegment(&pts[i], &pts[first], &pts[last])
+			d := pts[i].SphericalDistance(&closest, R)
+			if d > farthest {
+				index, farthest = i, d
+			}
+		}
+		if index != -1 {
+			keep[index] = true
+			stack = append(stack, [2]int{first, index}, [2]int{index, last})
+		}
+	}
+
+	var simplified []NVector
+	for i := range pts {
+		if keep[i] {
+			simplified = append(simplified, pts[i])
+		}
+	}
+	return simplified
+}
+
+// SmoothPath returns a smooth curve through *waypoints*, sampled at
+// *samplesPerSegment* points per segment, using a uniform Catmull-Rom spline
+// built from great circle interpolation in place of linear interpolation. The
+// curve is continuous in direction at each waypoint instead of turning
+// sharply. Waypoint i appears in the result at index i*samplesPerSegment.
+func SmoothPath(waypoints []NVector, samplesPerSegment int) []NVector {
+	n := len(waypoints)
+	if n < 2 {
+		return append([]NVector(nil), waypoints...)
+	}
+	if samplesPerSegment < 1 {
+		samplesPerSegment = 1
+	}
+
+	// control points, with phantom points extending the path at either end
+	ctrl := make([]Vec3, n+2)
+	for i := range waypoints {
+		ctrl[i+1] = normalize(&waypoints[i].Vec3)
+	}
+	ctrl[0] = slerp(&ctrl[1], &ctrl[2], -1)
+	ctrl[n+1] = slerp(&ctrl[n], &ctrl[n-1], -1)
+
+	path := make([]NVector, 0, (n-1)*samplesPerSegment+1)
+	for i := 1; i < n; i++ {
+		p0, p1, p2, p3 := &ctrl[i-1], &ctrl[i], &ctrl[i+1], &ctrl[i+2]
+		for k := 0; k < samplesPerSegment; k++ {
+			// Barry-Goldman pyramidal evaluation with knots at -1, 0, 1, 2
+			u := float64(k) / float64(samplesPerSegment)
+			a1 := slerp(p0, p1, u+1)
+			a2 := slerp(p1, p2, u)
+			a3 := slerp(p2, p3, u-1)
+			b1 := slerp(&a1, &a2, (u+1)/2)
+			b2 := slerp(&a2, &a3, u/2)
+			path = append(path, NVector{slerp(&b1, &b2, u)})
+		}
+	}
+	return append(path, NVector{ctrl[n]})
+}
+
+// InterpolatePath3D returns the position a fraction *frac* of the way along
+// the path through *waypoints*, measured by geodesic length on an ellipsoid.
+// Within each leg, the horizontal position follows the great circle and the
+// height varies linearly. Fractions are clamped to [0, 1].
+func InterpolatePath3D(waypoints []LonLatHeight, frac float64, ellps *Ellipsoid) LonLatHeight {
+	if len(waypoints) == 0 {
+		return LonLatHeight{}
+	}
+	frac = math.Max(0, math.Min(1, frac))
+
+	nvs := make([]NVector, len(waypoints))
+	for i, w := range waypoints {
+		ll := LonLat{w.Lon, w.Lat}
+		nvs[i] = ll.ToNVector()
+	}
+	lengths := make([]float64, len(waypoints)-1)
+	var total float64
+	for i := range lengths {
+		d, _, _, err := vincentyInverse(&nvs[i], &nvs[i+1], ellps)
+		if err != nil {
+			d = nvs[i].SphericalDistance(&nvs[i+1], ellps.meanRadius())
+		}
+		lengths[i] = d
+		total += d
+	}
+
+	target := frac * total
+	for i, d := range lengths {
+		if target > d && i < len(lengths)-1 {
+			target -= d
+			continue
+		}
+		f := 0.0
+		if d > 0 {
+			f = math.Min(target/d, 1)
+		}
+		nv := NVector{slerp(&nvs[i].Vec3, &nvs[i+1].Vec3, f)}
+		lon, lat := geodeticLonLat(&nv)
+		height := waypoints[i].Height + f*(waypoints[i+1].Height-waypoints[i].Height)
+		return LonLatHeight{lon, lat, height}
+	}
+	return waypoints[0]
+}
+
+// SelfIntersects returns whether any two non-adjacent segments of the path
+// through *pts* cross, and if so the indices of the first such pair of
+// segments found, where segment i runs from pts[i] to pts[i+1]. If no
+// crossing is found, the indices are -1. If the path is closed, with its last
+// vertex equal to its first, the first and last segments are considered
+// adjacent. Every pair of segments is tested, so the cost grows with the
+// square of the length of the path.
+func SelfIntersects(pts []NVector) (bool, int, int) {
+	nseg := len(pts) - 1
+	closed := nseg > 2 && pts[0] == pts[nseg]
+	for i := 0; i < nseg; i++ {
+		for j := i + 2; j < nseg; j++ {
+			if closed && i == 0 && j == nseg-1 {
+				continue
+			}
+			_, onBoth, err := SegmentIntersection(&pts[i], &pts[i+1], &pts[j], &pts[j+1])
+			if err == nil && onBoth {
+				return true, i, j
+			}
+		}
+	}
+	return false, -1, -1
+}
+
+// SplitAtAntimeridian divides the polyline through *pts* wherever a segment
+// crosses the antimeridian, identified by consecutive longitudes more than pi
+// apart, so that it can be drawn on a map without spanning the globe. Each
+// crossing ends one part and starts the next with a point on the
+// antimeridian, at longitude pi or -pi to match the side of the part and at
+// the latitude where the great circle segment crosses.
+func SplitAtAntimeridian(pts []LonLat) [][]LonLat {
+	if len(pts) == 0 {
+		return nil
+	}
+	var parts [][]LonLat
+	part := []LonLat{pts[0]}
+	for i := 1; i < len(pts); i++ {
+		prev, cur := pts[i-1], pts[i]
+		if math.Abs(cur.Lon-prev.Lon) > math.Pi {
+			// the chord meets the plane of the antimeridian directly beneath
+			// the crossing
+			a, b := prev.ToNVector(), cur.ToNVector()
+			t := a.Vec3[1] / (a.Vec3[1] - b.Vec3[1])
+			x := a.Vec3[0] + t*(b.Vec3[0]-a.Vec3[0])
+			z := a.Vec3[2] + t*(b.Vec3[2]-a.Vec3[2])
+			lat := math.Atan2(z, math.Abs(x))
+
+			side := math.Copysign(math.Pi, prev.Lon)
+			part = append(part, LonLat{side, lat})
+			parts = append(parts, part)
+			part = []LonLat{{-side, lat}, cur}
+			continue
+		}
+		part = append(part, cur)
+	}
+	return append(parts, part)
+}
@@ -0,0 +1,514 @@
+package nvector
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPathLengthPrecise(t *testing.T) {
+	// a long first leg followed by many tiny, identical back-and-forth steps
+	ll0, _ := NewLonLat(-60.0, -30.0)
+	llA, _ := NewLonLat(10.0, 45.0)
+	llB, _ := NewLonLat(10.0000001, 45.0)
+	R := 6371000.0
+
+	n := 1000000
+	points := make([]NVector, n+1)
+	points[0] = ll0.ToNVector()
+	for i := 1; i <= n; i++ {
+		if i%2 == 1 {
+			points[i] = llA.ToNVector()
+		} else {
+			points[i] = llB.ToNVector()
+		}
+	}
+
+	step := points[1].SphericalDistance(&points[2], R)
+	analytic := points[0].SphericalDistance(&points[1], R) + float64(n-1)*step
+
+	var naive float64
+	for i := 1; i < len(points); i++ {
+		naive += points[i-1].SphericalDistance(&points[i], R)
+	}
+	precise := PathLengthPrecise(points, R)
+
+	if math.Abs(precise-analytic) > 1e-9 {
+		t.Fail()
+	}
+	if math.Abs(precise-analytic) >= math.Abs(naive-analytic) {
+		t.Fail()
+	}
+}
+
+func TestPathLengthPreciseShort(t *testing.T) {
+	if PathLengthPrecise(nil, 1.0) != 0 {
+		t.Fail()
+	}
+	nv := NVector{Vec3{1, 0, 0}}
+	if PathLengthPrecise([]NVector{nv}, 1.0) != 0 {
+		t.Fail()
+	}
+}
+
+func TestTurnAngle(t *testing.T) {
+	ll1, _ := NewLonLat(-1, 0)
+	ll2, _ := NewLonLat(0, 0)
+	ll3, _ := NewLonLat(0, 1)
+	ll4, _ := NewLonLat(0, -1)
+	ll5, _ := NewLonLat(1, 0)
+	prev := ll1.ToNVector()
+	at := ll2.ToNVector()
+	north := ll3.ToNVector()
+	south := ll4.ToNVector()
+	east := ll5.ToNVector()
+
+	// heading east, turning north is a left turn
+	if !isclose(TurnAngle(&prev, &at, &north), -0.5*math.Pi, 10) {
+		t.Fail()
+	}
+	if !isclose(TurnAngle(&prev, &at, &south), 0.5*math.Pi, 10) {
+		t.Fail()
+	}
+	if !isclose(TurnAngle(&prev, &at, &east), 0, 10) {
+		t.Fail()
+	}
+	// doubling back
+	if !isclose(math.Abs(TurnAngle(&prev, &at, &prev)), math.Pi, 10) {
+		t.Fail()
+	}
+}
+
+func TestTurnAngleGreatCircle(t *testing.T) {
+	// no turn is made at a point on the great circle, even though the course
+	// changes from start to finish
+	ll1, _ := NewLonLat(-60, 40)
+	ll2, _ := NewLonLat(60, 40)
+	nv1 := ll1.ToNVector()
+	nv2 := ll2.ToNVector()
+	mid := NVector{slerp(&nv1.Vec3, &nv2.Vec3, 0.4)}
+	if !isclose(TurnAngle(&nv1, &mid, &nv2), 0, 10) {
+		t.Fail()
+	}
+}
+
+func TestTrackSimilarityIdentical(t *testing.T) {
+	track := lonLatRing([][2]float64{{-123.1, 49.3}, {-122.0, 48.0}, {-121.0, 48.5}, {-120.5, 47.0}})
+	if TrackSimilarity(track, track, 6371000) != 0 {
+		t.Fail()
+	}
+}
+
+func TestTrackSimilarityShifted(t *testing.T) {
+	// two tracks along parallel meridians
+	a := lonLatRing([][2]float64{{0, 0}, {0, 1}, {0, 2}, {0, 3}})
+	b := lonLatRing([][2]float64{{0.01, 0}, {0.01, 1}, {0.01, 2}, {0.01, 3}})
+	R := 6371000.0
+
+	shift := a[0].SphericalDistance(&b[0], R)
+	d := TrackSimilarity(a, b, R)
+	if !isclose(d, shift, 6) {
+		t.Error(d, shift)
+	}
+	if !isclose(TrackSimilarity(b, a, R), d, 9) {
+		t.Fail()
+	}
+}
+
+func TestTrackSimilarityReversed(t *testing.T) {
+	// a reversed track is far from the original, since couplings are monotone
+	a := lonLatRing([][2]float64{{0, 0}, {0, 1}, {0, 2}})
+	b := lonLatRing([][2]float64{{0, 2}, {0, 1}, {0, 0}})
+	if !isclose(TrackSimilarity(a, b, 1.0), a[0].SphericalDistance(&a[2], 1.0), 12) {
+		t.Fail()
+	}
+}
+
+func TestPathLength(t *testing.T) {
+	pts := lonLatRing([][2]float64{{-140, 49.25}, {-140, 48.25}, {-143, 48.25}})
+	R := 6370997.0
+	expected := pts[0].SphericalDistance(&pts[1], R) + pts[1].SphericalDistance(&pts[2], R)
+	if !isclose(PathLength(pts, R), expected, 6) {
+		t.Fail()
+	}
+	if PathLength(pts[:1], R) != 0 || PathLength(nil, R) != 0 {
+		t.Fail()
+	}
+}
+
+func TestPathLengthEllipsoid(t *testing.T) {
+	ellps := Ellipsoid{6378137.0, 6356752.314245}
+	pts := lonLatRing([][2]float64{{0, 0}, {0, 1}, {0, 2}})
+	length, err := PathLengthEllipsoid(pts, &ellps)
+	if err != nil {
+		t.Error(err)
+	}
+	// two degrees of meridian arc near the equator
+	if !isclose(length, 221149.453, 2) {
+		t.Error(length)
+	}
+
+	length, err = PathLengthEllipsoid(pts[:1], &ellps)
+	if err != nil || length != 0 {
+		t.Fail()
+	}
+}
+
+func TestPathLengthEllipsoidConvergence(t *testing.T) {
+	ellps := Ellipsoid{6378137.0, 6356752.314245}
+	pts := lonLatRing([][2]float64{{0, 0.5}, {179.7, -0.5}})
+	if _, err := PathLengthEllipsoid(pts, &ellps); err == nil {
+		t.Fail()
+	}
+}
+
+func TestGreatCirclePoints(t *testing.T) {
+	ll1, _ := NewLonLat(-123.1, 49.3)
+	ll2, _ := NewLonLat(-0.1, 51.5)
+	a := ll1.ToNVector()
+	b := ll2.ToNVector()
+
+	pts := GreatCirclePoints(&a, &b, 11)
+	if len(pts) != 11 {
+		t.Fatal(len(pts))
+	}
+	if !isclose(pts[0].SphericalDistance(&a, 1.0), 0, 12) || !isclose(pts[10].SphericalDistance(&b, 1.0), 0, 12) {
+		t.Fail()
+	}
+
+	step := a.SphericalDistance(&b, 1.0) / 10
+	for i := 1; i < len(pts); i++ {
+		if !isclose(pts[i-1].SphericalDistance(&pts[i], 1.0), step, 12) {
+			t.Fail()
+		}
+		if !isclose(pts[i].Magnitude(), 1, 12) {
+			t.Fail()
+		}
+		// every point lies on the great circle
+		normal := cross(&a.Vec3, &b.Vec3)
+		if !isclose(dot(&pts[i].Vec3, &normal), 0, 12) {
+			t.Fail()
+		}
+	}
+}
+
+func TestGreatCirclePointsFew(t *testing.T) {
+	a := NVector{Vec3{1, 0, 0}}
+	b := NVector{Vec3{0, 1, 0}}
+	if len(GreatCirclePoints(&a, &b, 0)) != 0 {
+		t.Fail()
+	}
+	pts := GreatCirclePoints(&a, &b, 1)
+	if len(pts) != 1 || pts[0] != a {
+		t.Fail()
+	}
+	if len(GreatCirclePoints(&a, &b, 2)) != 2 {
+		t.Fail()
+	}
+}
+
+func TestDensify(t *testing.T) {
+	pts := lonLatRing([][2]float64{{0, 0}, {0, 1}, {0, 1.05}, {2, 1.05}})
+	R := 6371000.0
+	maxSeg := 25000.0
+
+	dense := Densify(pts, maxSeg, R)
+	for i := 1; i < len(dense); i++ {
+		if dense[i-1].SphericalDistance(&dense[i], R) > maxSeg+1e-6 {
+			t.Fail()
+		}
+	}
+	if !isclose(PathLength(dense, R), PathLength(pts, R), 6) {
+		t.Fail()
+	}
+
+	// original vertices are retained, in order
+	j := 0
+	for i := range dense {
+		if j < len(pts) && dense[i] == pts[j] {
+			j++
+		}
+	}
+	if j != len(pts) {
+		t.Fail()
+	}
+
+	// the short middle segment is left alone
+	if len(Densify(pts[1:3], maxSeg, R)) != 2 {
+		t.Fail()
+	}
+}
+
+func TestClosestOnSegment(t *testing.T) {
+	a := lonLatRing([][2]float64{{0, 0}, {10, 0}, {5, 3}, {-5, 3}, {12, -1}})
+
+	// perpendicular foot within the arc
+	c := ClosestOnSegment(&a[2], &a[0], &a[1])
+	ll := c.ToLonLat()
+	if !isclose(ll.Lon*180/math.Pi, 5, 10) || !isclose(ll.Lat, 0, 10) {
+		t.Fail()
+	}
+
+	// beyond either end, the endpoints are closest
+	if ClosestOnSegment(&a[3], &a[0], &a[1]) != a[0] {
+		t.Fail()
+	}
+	if ClosestOnSegment(&a[4], &a[0], &a[1]) != a[1] {
+		t.Fail()
+	}
+}
+
+func TestMapMatch(t *testing.T) {
+	R := 6371e3
+	network := [][]NVector{
+		lonLatRing([][2]float64{{0, 0}, {1, 0}, {2, 0}}),
+		lonLatRing([][2]float64{{1, 0.1}, {1, 1}}),
+		lonLatRing([][2]float64{{3, 3}}),
+	}
+	track := lonLatRing([][2]float64{{0.5, 0.01}, {1.02, 0.5}, {1.5, -0.02}, {2.9, 2.9}})
+
+	matched := MapMatch(track, network, R)
+	if len(matched) != len(track) {
+		t.Fatal(len(matched))
+	}
+	expected := lonLatRing([][2]float64{{0.5, 0}, {1, 0.5}, {1.5, 0}, {3, 3}})
+	for i := range matched {
+		if matched[i].SphericalDistance(&expected[i], R) > 1 {
+			t.Error(i, matched[i].ToLonLat())
+		}
+		nearest := math.Inf(1)
+		for _, line := range network {
+			nearest = math.Min(nearest, DistanceToPolyline(&track[i], line, R))
+		}
+		if !isclose(track[i].SphericalDistance(&matched[i], R), nearest, 6) {
+			t.Error(i, "not on the nearest polyline")
+		}
+	}
+
+	if MapMatch(track, nil, R) != nil || MapMatch(track, [][]NVector{{}}, R) != nil {
+		t.Error("empty network should give nil")
+	}
+}
+
+func TestSimplify(t *testing.T) {
+	// a nearly straight path along the equator with one real corner
+	pts := lonLatRing([][2]float64{{0, 0}, {1, 0.0001}, {2, -0.0001}, {3, 0}, {3, 1}, {3.0001, 2}, {3, 3}})
+	R := 6371000.0
+
+	simplified := Simplify(pts, 100, R)
+	if len(simplified) != 3 {
+		t.Fatal(len(simplified))
+	}
+	if simplified[0] != pts[0] || simplified[1] != pts[3] || simplified[2] != pts[6] {
+		t.Fail()
+	}
+
+	// a tolerance below the wiggles keeps everything
+	if len(Simplify(pts, 1, R)) != len(pts) {
+		t.Fail()
+	}
+}
+
+func TestSimplifyShort(t *testing.T) {
+	pts := lonLatRing([][2]float64{{0, 0}, {1, 1}})
+	if len(Simplify(pts, 1e6, 6371000)) != 2 {
+		t.Fail()
+	}
+}
+
+func TestSmoothPath(t *testing.T) {
+	waypoints := lonLatRing([][2]float64{{0, 0}, {10, 0}, {10, 10}, {20, 12}, {30, 5}})
+	samples := 500
+	path := SmoothPath(waypoints, samples)
+	if len(path) != (len(waypoints)-1)*samples+1 {
+		t.Fatal(len(path))
+	}
+
+	for i := range waypoints {
+		if !isclose(path[i*samples].SphericalDistance(&waypoints[i], 1.0), 0, 10) {
+			t.Error(i)
+		}
+	}
+
+	// the raw path turns 90 degrees at the second waypoint, but the smoothed
+	// path turns gradually, and its direction is continuous at the waypoints
+	if !isclose(math.Abs(TurnAngle(&waypoints[0], &waypoints[1], &waypoints[2])), 0.5*math.Pi, 2) {
+		t.Fail()
+	}
+	for i := 1; i < len(path)-1; i++ {
+		if math.Abs(TurnAngle(&path[i-1], &path[i], &path[i+1])) > math.Pi/180 {
+			t.Error(i)
+		}
+	}
+}
+
+func TestSmoothPathTwoPoints(t *testing.T) {
+	// with two waypoints the spline is the great circle
+	waypoints := lonLatRing([][2]float64{{0, 0}, {40, 30}})
+	path := SmoothPath(waypoints, 10)
+	normal := cross(&waypoints[0].Vec3, &waypoints[1].Vec3)
+	for i := range path {
+		if !isclose(dot(&path[i].Vec3, &normal), 0, 10) {
+			t.Error(i)
+		}
+	}
+}
+
+func TestInterpolatePath3D(t *testing.T) {
+	ellps := Ellipsoid{6378137.0, 6356752.314245}
+	ll1, _ := NewLonLat(-123.1, 49.2)
+	ll2, _ := NewLonLat(-79.6, 43.7)
+	waypoints := []LonLatHeight{{ll1.Lon, ll1.Lat, 1000}, {ll2.Lon, ll2.Lat, 11000}}
+
+	mid := InterpolatePath3D(waypoints, 0.5, &ellps)
+	if !isclose(mid.Height, 6000, 8) {
+		t.Fail()
+	}
+	nv1 := ll1.ToNVector()
+	nv2 := ll2.ToNVector()
+	expected := NVector{slerp(&nv1.Vec3, &nv2.Vec3, 0.5)}
+	got := LonLat{mid.Lon, mid.Lat}
+	nvMid := got.ToNVector()
+	if !isclose(nvMid.AngleTo(&expected), 0, 12) {
+		t.Fail()
+	}
+
+	start := InterpolatePath3D(waypoints, 0, &ellps)
+	end := InterpolatePath3D(waypoints, 1, &ellps)
+	if !isclose(start.Height, 1000, 8) || !isclose(end.Height, 11000, 8) {
+		t.Fail()
+	}
+	if !isclose(end.Lon, ll2.Lon, 12) || !isclose(end.Lat, ll2.Lat, 12) {
+		t.Fail()
+	}
+}
+
+func TestInterpolatePath3DMultipleLegs(t *testing.T) {
+	ellps := Ellipsoid{6378137.0, 6356752.314245}
+	ll1, _ := NewLonLat(0, 0)
+	ll2, _ := NewLonLat(0, 1)
+	ll3, _ := NewLonLat(0, 3)
+	waypoints := []LonLatHeight{{ll1.Lon, ll1.Lat, 0}, {ll2.Lon, ll2.Lat, 300}, {ll3.Lon, ll3.Lat, 300}}
+
+	// a third of the way along is near the middle waypoint
+	p := InterpolatePath3D(waypoints, 1.0/3, &ellps)
+	if !isclose(p.Lat*180/math.Pi, 1, 2) || !isclose(p.Height, 300, 0) {
+		t.Error(p)
+	}
+	p = InterpolatePath3D(waypoints, 2.0/3, &ellps)
+	if !isclose(p.Lat*180/math.Pi, 2, 2) || p.Height != 300 {
+		t.Error(p)
+	}
+}
+
+func TestSelfIntersects(t *testing.T) {
+	// a bow tie crosses itself between its first and third segments
+	bowtie := lonLatRing([][2]float64{{0, 0}, {10, 10}, {10, 0}, {0, 10}})
+	crosses, i, j := SelfIntersects(bowtie)
+	if !crosses || i != 0 || j != 2 {
+		t.Error(crosses, i, j)
+	}
+
+	// a closed square doesn't, despite its first and last segments meeting
+	square := lonLatRing([][2]float64{{0, 0}, {10, 0}, {10, 10}, {0, 10}, {0, 0}})
+	crosses, i, j = SelfIntersects(square)
+	if crosses || i != -1 || j != -1 {
+		t.Error(crosses, i, j)
+	}
+
+	if crosses, _, _ := SelfIntersects(square[:2]); crosses {
+		t.Fail()
+	}
+}
+
+func TestResampleByDistance(t *testing.T) {
+	R := 6371e3
+	pos, _ := NewLonLat(12, 55)
+	start := pos.ToNVector()
+	mid := start.Forward(0.3, 2500, R)
+	end := mid.Forward(1.8, 1750, R)
+	pts := []NVector{start, mid, end}
+
+	resampled := ResampleByDistance(pts, 1000, R)
+	if len(resampled) != 6 {
+		t.Fatal(len(resampled))
+	}
+	if resampled[0] != start || resampled[5] != end {
+		t.Fail()
+	}
+
+	// samples fall every 1000 m along the path, across the corner at *mid*
+	if !isclose(start.SphericalDistance(&resampled[2], R), 2000, 6) {
+		t.Fail()
+	}
+	if !isclose(resampled[3].SphericalDistance(&mid, R), 500, 6) {
+		t.Fail()
+	}
+	if !isclose(resampled[4].SphericalDistance(&end, R), 250, 6) {
+		t.Fail()
+	}
+	if !isclose(PathLength(pts, R), 4250, 6) {
+		t.Fail()
+	}
+
+	// a path whose length is a whole number of intervals doesn't repeat its
+	// end
+	pts = []NVector{start, start.Forward(1, 3000, R)}
+	if n := len(ResampleByDistance(pts, 1000, R)); n != 4 {
+		t.Error(n)
+	}
+}
+
+func TestSplitAtAntimeridian(t *testing.T) {
+	R := 6371e3
+	a, _ := NewLonLat(170, 20)
+	b, _ := NewLonLat(-160, 40)
+	c, _ := NewLonLat(-150, 45)
+	parts := SplitAtAntimeridian([]LonLat{*a, *b, *c})
+	if len(parts) != 2 || len(parts[0]) != 2 || len(parts[1]) != 3 {
+		t.Fatal(parts)
+	}
+	end, start := parts[0][1], parts[1][0]
+	if end.Lon != math.Pi || start.Lon != -math.Pi || end.Lat != start.Lat {
+		t.Fail()
+	}
+
+	// the crossing lies on the great circle segment
+	nva, nvb := a.ToNVector(), b.ToNVector()
+	crossing := end.ToNVector()
+	if !isclose(nva.SphericalDistance(&crossing, R)+crossing.SphericalDistance(&nvb, R),
+		nva.SphericalDistance(&nvb, R), 4) {
+		t.Fail()
+	}
+
+	// a path that doesn't cross is returned whole
+	parts = SplitAtAntimeridian([]LonLat{*b, *c})
+	if len(parts) != 1 || len(parts[0]) != 2 {
+		t.Fail()
+	}
+}
+
+func TestDistanceToPolyline(t *testing.T) {
+	R := 6371e3
+	line := lonLatRing([][2]float64{{0, 0}, {1, 0}, {1, 1}})
+
+	// nearest the interior of the first segment
+	pt, _ := NewLonLat(0.5, -0.1)
+	nv := pt.ToNVector()
+	if !isclose(DistanceToPolyline(&nv, line, R), 0.1*math.Pi/180*R, 2) {
+		t.Fail()
+	}
+
+	// nearest the corner
+	pt, _ = NewLonLat(1.1, -0.1)
+	nv = pt.ToNVector()
+	if !isclose(DistanceToPolyline(&nv, line, R), nv.SphericalDistance(&line[1], R), 6) {
+		t.Fail()
+	}
+
+	if !isclose(DistanceToPolyline(&nv, line[:1], R), nv.SphericalDistance(&line[0], R), 6) {
+		t.Fail()
+	}
+	if !math.IsInf(DistanceToPolyline(&nv, nil, R), 1) {
+		t.Fail()
+	}
+}
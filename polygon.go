@@ -0,0 +1,573 @@
+package nvector
+
+import (
+	"math"
+	"sort"
+)
+
+// signedTriangleArea returns the area of the spherical triangle *a*, *b*, *c*
+// on a unit sphere, positive when the vertices run counter-clockwise
+func signedTriangleArea(a, b, c *Vec3) float64 {
+	bc := cross(b, c)
+	triple := dot(a, &bc)
+	return 2 * math.Atan2(triple, 1+dot(a, b)+dot(b, c)+dot(c, a))
+}
+
+// signedRingArea returns the area enclosed by *ring* on a unit sphere,
+// positive when the vertices run counter-clockwise
+func signedRingArea(ring []NVector) float64 {
+	var area float64
+	for i := 1; i < len(ring)-1; i++ {
+		area += signedTriangleArea(&ring[0].Vec3, &ring[i].Vec3, &ring[i+1].Vec3)
+	}
+	return area
+}
+
+// PointInPolygon returns whether *pt* falls inside the spherical polygon
+// defined by *ring*, in either winding order. The ring is implicitly closed,
+// and the polygon is taken to be the smaller of the two regions that the ring
+// divides the sphere into.
+func PointInPolygon(pt *NVector, ring []NVector) bool {
+	if len(ring) < 3 {
+		return false
+	}
+	p := normalize(&pt.Vec3)
+
+	// sum the angles subtended at pt by each edge, measured in the plane
+	// tangent at pt
+	var winding float64
+	for i := range ring {
+		a := &ring[i].Vec3
+		b := &ring[(i+1)%len(ring)].Vec3
+		pa, pb := dot(a, &p), dot(b, &p)
+		ta := Vec3{a[0] - pa*p[0], a[1] - pa*p[1], a[2] - pa*p[2]}
+		tb := Vec3{b[0] - pb*p[0], b[1] - pb*p[1], b[2] - pb*p[2]}
+		tab := cross(&ta, &tb)
+		winding += math.Atan2(dot(&tab, &p), dot(&ta, &tb))
+	}
+
+	// a ring also winds around the antipodes of its interior, in the opposite
+	// sense
+	if math.Abs(winding) < math.Pi {
+		return false
+	}
+	return (winding > 0) == (signedRingArea(ring) > 0)
+}
+
+// PolygonCrossing is a point where a route crosses a polygon boundary
+type PolygonCrossing struct {
+	Point        NVector
+	SegmentIndex int
+	Entering     bool
+}
+
+// RoutePolygonCrossings returns every point where the great circle route
+// through *path* crosses the boundary of *polygon*, in order along the route.
+// Each crossing records the index of the route segment it lies on and whether
+// the route is entering or leaving the polygon there. A crossing at a vertex
+// of the route is reported once, on the segment ending there.
+func RoutePolygonCrossings(path []NVector, polygon []NVector) []PolygonCrossing {
+	if len(path) < 2 || len(polygon) < 3 {
+		return nil
+	}
+
+	var crossings []PolygonCrossing
+	inside := PointInPolygon(&path[0], polygon)
+	for i := 0; i < len(path)-1; i++ {
+		var points []NVector
+		var along []float64
+		for j := range polygon {
+			next := &polygon[(j+1)%len(polygon)]
+			pt, err := Intersection(&path[i], &path[i+1], &polygon[j], next)
+			if err != nil {
+				continue
+			}
+
+			// a crossing through a polygon vertex is found on both edges
+			d := path[i].SphericalDistance(&pt, 1.0)
+			duplicate := false
+			for k := range points {
+				if math.Abs(along[k]-d) < 1e-12 {
+					duplicate = true
+				}
+			}
+			if !duplicate {
+				points = append(points, pt)
+				along = append(along, d)
+			}
+		}
+
+		order := make([]int, len(points))
+		for k := range order {
+			order[k] = k
+		}
+		sort.Slice(order, func(m, n int) bool { return along[order[m]] < along[order[n]] })
+		for _, k := range order {
+			// a crossing through a route vertex is found on both segments,
+			// and is recorded on the first
+			if n := len(crossings); n > 0 && crossings[n-1].SegmentIndex == i-1 &&
+				crossings[n-1].Point.AngleTo(&points[k]) <= IntersectionToleranceRad {
+				continue
+			}
+			crossings = append(crossings, PolygonCrossing{points[k], i, !inside})
+			inside = !inside
+		}
+	}
+	return crossings
+}
+
+// triangleMoment returns the integral of the position vector over the
+// spherical triangle *a*, *b*, *c* on a unit sphere, i.e. the triangle's area
+// times its mean position. It is negated if the vertices run clockwise.
+func triangleMoment(a, b, c *Vec3) Vec3 {
+	var moment Vec3
+	for _, edge := range [3][2]*Vec3{{a, b}, {b, c}, {c, a}} {
+		normal := cross(edge[0], edge[1])
+		theta := math.Atan2(normal.Magnitude(), dot(edge[0], edge[1]))
+		n := normalize(&normal)
+		moment[0] += 0.5 * theta * n[0]
+		moment[1] += 0.5 * theta * n[1]
+		moment[2] += 0.5 * theta * n[2]
+	}
+	return moment
+}
+
+// PolygonCentroid returns the area-weighted centroid of the spherical polygon
+// defined by *ring*, which is a better place for a label than the mean of the
+// vertices. The polygon is divided into a fan of triangles from its first
+// vertex, and the centroids of the triangles are weighted by their signed
+// areas, so that concave polygons are handled correctly. The result lies
+// inside any convex polygon and doesn't depend on the radius of the sphere.
+// DegenerateGeometryError is returned for polygons with no area.
+func PolygonCentroid(ring []NVector) (NVector, error) {
+	var sum Vec3
+	var total float64
+	for i := 1; i < len(ring)-1; i++ {
+		a, b, c := &ring[0].Vec3, &ring[i].Vec3, &ring[i+1].Vec3
+		moment := triangleMoment(a, b, c)
+		sum[0] += moment[0]
+		sum[1] += moment[1]
+		sum[2] += moment[2]
+		total += signedTriangleArea(a, b, c)
+	}
+
+	if math.Abs(total) < 1e-15 || sum.Magnitude() == 0 {
+		return NVector{}, DegenerateGeometryError{"polygon has no area"}
+	}
+	if total < 0 {
+		sum = Vec3{-sum[0], -sum[1], -sum[2]}
+	}
+	return NVector{normalize(&sum)}, nil
+}
+
+// PolygonIsClockwise returns whether the vertices of *ring* run clockwise when
+// viewed from above the sphere, taking the polygon to be the smaller of the
+// two regions the ring encloses.
+func PolygonIsClockwise(ring []NVector) bool {
+	return signedRingArea(ring) < 0
+}
+
+// NormalizeRing returns a canonical copy of *ring*: consecutive duplicate
+// vertices are removed, the vertex order is reversed if necessary so that the
+// ring runs counter-clockwise (if *wantCCW*) or clockwise, and the ring is
+// explicitly closed by repeating its first vertex at the end.
+func NormalizeRing(ring []NVector, wantCCW bool) []NVector {
+	var clean []NVector
+	for i := range ring {
+		if len(clean) > 0 && clean[len(clean)-1].SphericalDistance(&ring[i], 1.0) < 1e-12 {
+			continue
+		}
+		clean = append(clean, ring[i])
+	}
+	for len(clean) > 1 && clean[0].SphericalDistance(&clean[len(clean)-1], 1.0) < 1e-12 {
+		clean = clean[:len(clean)-1]
+	}
+	if len(clean) == 0 {
+		return clean
+	}
+
+	if PolygonIsClockwise(clean) == wantCCW {
+		for i, j := 0, len(clean)-1; i < j; i, j = i+1, j-1 {
+			clean[i], clean[j] = clean[j], clean[i]
+		}
+	}
+	return append(clean, clean[0])
+}
+
+// Circle returns *n* points on the small circle of great circle radius
+// *radius* around *center*, on a sphere with radius *R*. The points are found
+// with Forward at evenly spaced azimuths starting from due north, so they run
+// clockwise. The ring is open: the first point is not repeated at the end,
+// as the polygon functions treat rings as implicitly closed.
+func Circle(center *NVector, radius float64, R float64, n int) []NVector {
+	if n < 1 {
+		return nil
+	}
+	ring := make([]NVector, n)
+	for i := range ring {
+		ring[i] = center.Forward(2*math.Pi*float64(i)/float64(n), radius, R)
+	}
+	return ring
+}
+
+// ringBoundary returns *ring* with its first vertex repeated at the end, if it
+// isn't already, so that the boundary can be treated as a polyline
+func ringBoundary(ring []NVector) []NVector {
+	if len(ring) > 1 && ring[0] != ring[len(ring)-1] {
+		return append(append([]NVector(nil), ring...), ring[0])
+	}
+	return ring
+}
+
+// DistanceToPolygon returns the distance on a sphere with radius *R* from *pt*
+// to the polygon defined by *ring*, which is zero if *pt* is inside it and
+// otherwise the distance to the nearest edge. The polygon is interpreted as
+// by PointInPolygon.
+func DistanceToPolygon(pt *NVector, ring []NVector, R float64) float64 {
+	if PointInPolygon(pt, ring) {
+		return 0
+	}
+	return DistanceToPolyline(pt, ringBoundary(ring), R)
+}
+
+// SignedDistanceToPolygon returns the distance on a sphere with radius *R*
+// from *pt* to the nearest edge of the polygon defined by *ring*, negated if
+// *pt* is inside the polygon
+func SignedDistanceToPolygon(pt *NVector, ring []NVector, R float64) float64 {
+	d := DistanceToPolyline(pt, ringBoundary(ring), R)
+	if PointInPolygon(pt, ring) {
+		return -d
+	}
+	return d
+}
+
+// ConvexHull returns the spherical convex hull of *pts* as an open ring of
+// the input points, running counter-clockwise, which can be passed directly
+// to the polygon functions. The points are projected gnomonically about their
+// mean position, where great circles become straight lines, and the planar
+// hull is found by Andrew's monotone chain. The hull of points spread beyond
+// a hemisphere isn't well defined, and DegenerateGeometryError is returned if
+// any point lies 90⁰ or more from the mean position, as well as when there
+// are fewer than three distinct points or they all lie on one great circle.
+func ConvexHull(pts []NVector) ([]NVector, error) {
+	if len(pts) < 3 {
+		return nil, DegenerateGeometryError{"fewer than three points"}
+	}
+	var sum Vec3
+	for i := range pts {
+		p := normalize(&pts[i].Vec3)
+		sum = Vec3{sum[0] + p[0], sum[1] + p[1], sum[2] + p[2]}
+	}
+	center := NVector{normalize(&sum)}
+	if center.Vec3 == (Vec3{}) {
+		return nil, DegenerateGeometryError{"points have no mean position"}
+	}
+
+	type projected struct {
+		x, y float64
+		idx  int
+	}
+	proj := make([]projected, len(pts))
+	for i := range pts {
+		x, y, err := center.Gnomonic(&pts[i])
+		if err != nil {
+			return nil, DegenerateGeometryError{"points span more than a hemisphere"}
+		}
+		proj[i] = projected{x, y, i}
+	}
+	sort.Slice(proj, func(i, j int) bool {
+		if proj[i].x != proj[j].x {
+			return proj[i].x < proj[j].x
+		}
+		return proj[i].y < proj[j].y
+	})
+
+	turn := func(o, a, b projected) float64 {
+		return (a.x-o.x)*(b.y-o.y) - (a.y-o.y)*(b.x-o.x)
+	}
+	hull := make([]projected, 0, 2*len(proj))
+	for _, p := range proj {
+		for len(hull) >= 2 && turn(hull[len(hull)-2], hull[len(hull)-1], p) <= 0 {
+			hull = hull[:len(hull)-1]
+		}
+		hull = append(hull, p)
+	}
+	lower := len(hull) + 1
+	for i := len(proj) - 2; i >= 0; i-- {
+		p := proj[i]
+		for len(hull) >= lower && turn(hull[len(hull)-2], hull[len(hull)-1], p) <= 0 {
+			hull = hull[:len(hull)-1]
+		}
+		hull = append(hull, p)
+	}
+	hull = hull[:len(hull)-1]
+	if len(hull) < 3 {
+		return nil, DegenerateGeometryError{"points are collinear"}
+	}
+
+	ring := make([]NVector, len(hull))
+	for i, p := range hull {
+		ring[i] = pts[p.idx]
+	}
+	return ring, nil
+}
+
+// bufferSide returns the offset of *line* at *radius* on its right-hand side,
+// followed by a round cap around its final vertex, up to but excluding the
+// start of the offset on the left-hand side. Outside corners are rounded and
+// inside corners mitred.
+func bufferSide(line []NVector, radius, R float64, segmentsPerCap int) []NVector {
+	n := len(line)
+	var side []NVector
+	arc := func(at *NVector, from, sweep float64, steps, last int) {
+		for j := 0; j <= last; j++ {
+			side = append(side, at.Forward(from+sweep*float64(j)/float64(steps), radius, R))
+		}
+	}
+
+	out := bearing(&line[0].Vec3, &line[1].Vec3)
+	side = append(side, line[0].Forward(out+0.5*math.Pi, radius, R))
+	for i := 1; i < n; i++ {
+		in := wrapAngle(bearing(&line[i].Vec3, &line[i-1].Vec3) + math.Pi)
+		if i == n-1 {
+			// the cap stops short of the start of the other side
+			arc(&line[i], in+0.5*math.Pi, -math.Pi, segmentsPerCap, segmentsPerCap-1)
+			break
+		}
+		out = bearing(&line[i].Vec3, &line[i+1].Vec3)
+		side = offsetCorner(side, &line[i], in, out, radius, R, segmentsPerCap)
+	}
+	return side
+}
+
+// minOffsetTurn is the smallest turn in radians at which offsetCorner rounds
+// a corner
+const minOffsetTurn = 1e-6
+
+// offsetCorner appends to *side* the offset at *radius* to the right of a
+// path turning at *at* from bearing *in* to bearing *out*. Corners turning
+// left, which bulge toward the offset, are rounded with up to
+// *segmentsPerCap* segments per half turn, and corners turning right are
+// mitred. Corners turning by less than minOffsetTurn are treated as
+// straight, giving a single point rather than two that nearly coincide.
+func offsetCorner(side []NVector, at *NVector, in, out, radius, R float64, segmentsPerCap int) []NVector {
+	turn := wrapAngle(out - in)
+	switch {
+	case turn < -minOffsetTurn:
+		steps := int(math.Ceil(-turn / math.Pi * float64(segmentsPerCap)))
+		if steps < 1 {
+			steps = 1
+		}
+		for j := 0; j <= steps; j++ {
+			side = append(side, at.Forward(in+0.5*math.Pi+turn*float64(j)/float64(steps), radius, R))
+		}
+	case math.Cos(0.5*turn) > 0.25 && math.Sin(radius/R) < math.Cos(0.5*turn):
+		// the mitre lies on the bisector, where it is *radius* from both
+		// great circles
+		miter := math.Asin(math.Sin(radius/R)/math.Cos(0.5*turn)) * R
+		side = append(side, at.Forward(in+0.5*turn+0.5*math.Pi, miter, R))
+	default:
+		// too sharp a turn to mitre
+		side = append(side, at.Forward(in+0.5*math.Pi, radius, R),
+			at.Forward(out+0.5*math.Pi, radius, R))
+	}
+	return side
+}
+
+// BufferPath returns a closed, counter-clockwise ring approximating the region
+// within *radius* of the great circle path through *line* on a sphere with
+// radius *R*. The ring follows the path at *radius* on either side, with
+// round caps of *segmentsPerCap* segments at each end and rounded outside
+// corners. Inside corners are mitred, so the approximation is poorest where
+// the path turns sharply over distances shorter than *radius*. A single
+// vertex gives a circle, and an empty path gives nil.
+func BufferPath(line []NVector, radius, R float64, segmentsPerCap int) []NVector {
+	if segmentsPerCap < 1 {
+		segmentsPerCap = 1
+	}
+
+	// drop repeated vertices, which have no direction
+	var pts []NVector
+	for i := range line {
+		if len(pts) == 0 || pts[len(pts)-1].AngleTo(&line[i]) > 0 {
+			pts = append(pts, line[i])
+		}
+	}
+	switch len(pts) {
+	case 0:
+		return nil
+	case 1:
+		return NormalizeRing(Circle(&pts[0], radius, R, 2*segmentsPerCap), true)
+	}
+
+	reversed := make([]NVector, len(pts))
+	for i := range pts {
+		reversed[len(pts)-1-i] = pts[i]
+	}
+	ring := bufferSide(pts, radius, R, segmentsPerCap)
+	ring = append(ring, bufferSide(reversed, radius, R, segmentsPerCap)...)
+	return append(ring, ring[0])
+}
+
+// offsetSegmentsPerCap is the number of segments used by OffsetPolygon to
+// round a corner turning through half a circle
+const offsetSegmentsPerCap = 16
+
+// OffsetPolygon returns the polygon *ring* offset by *distance* on a sphere
+// with radius *R*, outward for a positive distance and inward for a negative
+// one, as a closed, counter-clockwise ring. Corners bulging toward the
+// offset are rounded, as in BufferPath, and the others mitred. An inward
+// offset of a concave polygon can pinch the polygon into pieces or make it
+// vanish, and rather than clean the result, DegenerateGeometryError is
+// returned when the offset ring intersects itself or comes nearer to the
+// original ring than *distance*. It is also returned for rings with fewer
+// than three distinct vertices.
+func OffsetPolygon(ring []NVector, distance, R float64) ([]NVector, error) {
+	// the offset is taken to the right of travel, which is outside a
+	// counter-clockwise ring
+	pts := NormalizeRing(ring, distance >= 0)
+	if len(pts) < 4 {
+		return nil, DegenerateGeometryError{"ring has fewer than three vertices"}
+	}
+	pts = pts[:len(pts)-1]
+	n := len(pts)
+
+	var offset []NVector
+	for i := range pts {
+		prev, next := &pts[(i+n-1)%n], &pts[(i+1)%n]
+		in := wrapAngle(bearing(&pts[i].Vec3, &prev.Vec3) + math.Pi)
+		out := bearing(&pts[i].Vec3, &next.Vec3)
+		offset = offsetCorner(offset, &pts[i], in, out, math.Abs(distance), R, offsetSegmentsPerCap)
+	}
+
+	offset = append(offset, offset[0])
+	if crosses, _, _ := SelfIntersects(offset); crosses {
+		return nil, DegenerateGeometryError{"offset polygon intersects itself"}
+	}
+	// a collapsing polygon turns inside out, leaving vertices nearer to the
+	// original boundary than the offset distance, or on its other side
+	for i := range offset {
+		d := SignedDistanceToPolygon(&offset[i], pts, R)
+		if d*math.Copysign(1, distance) < (1-1e-6)*math.Abs(distance) {
+			return nil, DegenerateGeometryError{"offset polygon collapses"}
+		}
+	}
+	return NormalizeRing(offset, true), nil
+}
+
+// Triangulate divides the simple polygon defined by *ring* into spherical
+// triangles by ear clipping. The triangles share the winding order of the
+// ring, and their areas sum to the area of the polygon. The ring may be open
+// or closed. DegenerateGeometryError is returned for rings with fewer than
+// three vertices or that intersect themselves.
+func Triangulate(ring []NVector) ([][3]NVector, error) {
+	if len(ring) > 1 && ring[0] == ring[len(ring)-1] {
+		ring = ring[:len(ring)-1]
+	}
+	if len(ring) < 3 {
+		return nil, DegenerateGeometryError{"ring has fewer than three vertices"}
+	}
+	if crosses, _, _ := SelfIntersects(ringBoundary(ring)); crosses {
+		return nil, DegenerateGeometryError{"ring intersects itself"}
+	}
+	sense := 1.0
+	if signedRingArea(ring) < 0 {
+		sense = -1.0
+	}
+
+	// inside returns whether *p* lies within the triangle *a*, *b*, *c*,
+	// taken counter-clockwise
+	inside := func(p, a, b, c *Vec3) bool {
+		ab, bc, ca := cross(a, b), cross(b, c), cross(c, a)
+		return dot(p, &ab) >= 0 && dot(p, &bc) >= 0 && dot(p, &ca) >= 0
+	}
+
+	remaining := make([]int, len(ring))
+	for i := range remaining {
+		remaining[i] = i
+	}
+	triangles := make([][3]NVector, 0, len(ring)-2)
+	for len(remaining) > 3 {
+		n := len(remaining)
+		clipped := false
+		for i := 0; i < n; i++ {
+			a := &ring[remaining[(i+n-1)%n]].Vec3
+			b := &ring[remaining[i]].Vec3
+			c := &ring[remaining[(i+1)%n]].Vec3
+			if sense*signedTriangleArea(a, b, c) <= 0 {
+				continue
+			}
+			if sense < 0 {
+				a, c = c, a
+			}
+			ear := true
+			for j := 0; j < n; j++ {
+				if j == i || j == (i+n-1)%n || j == (i+1)%n {
+					continue
+				}
+				if inside(&ring[remaining[j]].Vec3, a, b, c) {
+					ear = false
+					break
+				}
+			}
+			if !ear {
+				continue
+			}
+			triangles = append(triangles, [3]NVector{
+				ring[remaining[(i+n-1)%n]], ring[remaining[i]], ring[remaining[(i+1)%n]]})
+			remaining = append(remaining[:i], remaining[i+1:]...)
+			clipped = true
+			break
+		}
+		if !clipped {
+			return nil, DegenerateGeometryError{"no ear found to clip"}
+		}
+	}
+	return append(triangles, [3]NVector{ring[remaining[0]], ring[remaining[1]], ring[remaining[2]]}), nil
+}
+
+// SphericalPolygonArea returns the area of the polygon defined by *ring* on a
+// sphere with radius *R*, as the sum of signed triangle areas. The ring is
+// implicitly closed and may run in either direction, and it should not
+// intersect itself.
+func SphericalPolygonArea(ring []NVector, R float64) float64 {
+	return math.Abs(signedRingArea(ring)) * R * R
+}
+
+// authalic returns the radius of the sphere with the same surface area as the
+// ellipsoid, and a function mapping geodetic latitudes to authalic latitudes,
+// which preserve area when the ellipsoid is mapped onto that sphere
+func (ellps *Ellipsoid) authalic() (float64, func(float64) float64) {
+	e2 := 1 - ellps.b*ellps.b/(ellps.a*ellps.a)
+	if e2 < 1e-15 {
+		return ellps.a, func(lat float64) float64 { return lat }
+	}
+	e := math.Sqrt(e2)
+	q := func(lat float64) float64 {
+		s := math.Sin(lat)
+		return (1 - e2) * (s/(1-e2*s*s) + math.Atanh(e*s)/e)
+	}
+	qp := q(0.5 * math.Pi)
+	Rq := ellps.a * math.Sqrt(0.5*qp)
+	return Rq, func(lat float64) float64 {
+		return math.Asin(math.Max(-1, math.Min(1, q(lat)/qp)))
+	}
+}
+
+// EllipsoidalPolygonArea returns the area in square meters of the polygon
+// defined by *ring* on the ellipsoid. The vertices are mapped to their
+// authalic latitudes on the sphere of equal area, which preserves area, and
+// the spherical area found there. Edges are treated as geodesics of the
+// authalic sphere rather than of the ellipsoid, which differ negligibly for
+// edges of ordinary length. On a sphere this agrees with
+// SphericalPolygonArea.
+func EllipsoidalPolygonArea(ring []NVector, ellps *Ellipsoid) float64 {
+	Rq, toAuthalic := ellps.authalic()
+	mapped := make([]NVector, len(ring))
+	for i := range ring {
+		lon, lat := geodeticLonLat(&ring[i])
+		ll := LonLat{lon, toAuthalic(lat)}
+		mapped[i] = ll.ToNVector()
+	}
+	return SphericalPolygonArea(mapped, Rq)
+}
@@ -0,0 +1,566 @@
+package nvector
+
+import (
+	"math"
+	"testing"
+)
+
+func lonLatRing(coords [][2]float64) []NVector {
+	ring := make([]NVector, len(coords))
+	for i, c := range coords {
+		ll, _ := NewLonLat(c[0], c[1])
+		ring[i] = ll.ToNVector()
+	}
+	return ring
+}
+
+func TestPointInPolygon(t *testing.T) {
+	square := lonLatRing([][2]float64{{-10, -10}, {10, -10}, {10, 10}, {-10, 10}})
+	reversed := lonLatRing([][2]float64{{-10, 10}, {10, 10}, {10, -10}, {-10, -10}})
+
+	cases := []struct {
+		lon, lat float64
+		inside   bool
+	}{
+		{0, 0, true},
+		{9, -9, true},
+		{11, 0, false},
+		{0, 45, false},
+		{180, 0, false}, // antipode of the interior
+	}
+	for _, c := range cases {
+		ll, _ := NewLonLat(c.lon, c.lat)
+		nv := ll.ToNVector()
+		if PointInPolygon(&nv, square) != c.inside {
+			t.Error(c.lon, c.lat)
+		}
+		if PointInPolygon(&nv, reversed) != c.inside {
+			t.Error("reversed", c.lon, c.lat)
+		}
+	}
+}
+
+func TestPointInPolygonConcave(t *testing.T) {
+	// L-shaped polygon
+	ring := lonLatRing([][2]float64{{0, 0}, {4, 0}, {4, 1}, {1, 1}, {1, 4}, {0, 4}})
+	ll1, _ := NewLonLat(0.5, 3)
+	ll2, _ := NewLonLat(3, 3)
+	nv1 := ll1.ToNVector()
+	nv2 := ll2.ToNVector()
+	if !PointInPolygon(&nv1, ring) {
+		t.Fail()
+	}
+	if PointInPolygon(&nv2, ring) {
+		t.Fail()
+	}
+}
+
+func TestRoutePolygonCrossings(t *testing.T) {
+	polygon := lonLatRing([][2]float64{{-10, -10}, {10, -10}, {10, 10}, {-10, 10}})
+	path := lonLatRing([][2]float64{{-20, 1}, {0, 0.5}, {20, 0}})
+
+	crossings := RoutePolygonCrossings(path, polygon)
+	if len(crossings) != 2 {
+		t.Fatal(len(crossings))
+	}
+
+	in := crossings[0].Point.ToLonLat()
+	out := crossings[1].Point.ToLonLat()
+	if !crossings[0].Entering || crossings[0].SegmentIndex != 0 || !isclose(in.Lon*180/math.Pi, -10, 8) {
+		t.Fail()
+	}
+	if crossings[1].Entering || crossings[1].SegmentIndex != 1 || !isclose(out.Lon*180/math.Pi, 10, 8) {
+		t.Fail()
+	}
+}
+
+func TestRoutePolygonCrossingsAtVertex(t *testing.T) {
+	polygon := lonLatRing([][2]float64{{-10, -10}, {10, -10}, {10, 10}, {-10, 10}})
+	path := lonLatRing([][2]float64{{-20, 0}, {-10, 0}, {0, 0}})
+
+	crossings := RoutePolygonCrossings(path, polygon)
+	if len(crossings) != 1 {
+		t.Fatal(len(crossings))
+	}
+	if !crossings[0].Entering || crossings[0].SegmentIndex != 0 || crossings[0].Point.AngleTo(&path[1]) > 1e-12 {
+		t.Error(crossings[0])
+	}
+}
+
+func TestRoutePolygonCrossingsMiss(t *testing.T) {
+	polygon := lonLatRing([][2]float64{{-10, -10}, {10, -10}, {10, 10}, {-10, 10}})
+	path := lonLatRing([][2]float64{{-20, 20}, {20, 20}})
+	if len(RoutePolygonCrossings(path, polygon)) != 0 {
+		t.Fail()
+	}
+}
+
+func TestPolygonCentroidConvex(t *testing.T) {
+	ring := lonLatRing([][2]float64{{10, 10}, {20, 10}, {22, 25}, {12, 18}})
+	centroid, err := PolygonCentroid(ring)
+	if err != nil {
+		t.Error(err)
+	}
+	if !PointInPolygon(&centroid, ring) {
+		t.Fail()
+	}
+
+	// winding order doesn't matter
+	reversed := []NVector{ring[3], ring[2], ring[1], ring[0]}
+	centroid2, _ := PolygonCentroid(reversed)
+	if !isclose(centroid.SphericalDistance(&centroid2, 1.0), 0, 10) {
+		t.Fail()
+	}
+}
+
+func TestPolygonCentroidLShape(t *testing.T) {
+	ring := lonLatRing([][2]float64{{0, 0}, {4, 0}, {4, 1}, {1, 1}, {1, 4}, {0, 4}})
+	centroid, err := PolygonCentroid(ring)
+	if err != nil {
+		t.Error(err)
+	}
+
+	// the planar centroid of the L is at (19/14, 19/14), nearer the corner
+	// than the vertex mean at (5/3, 5/3)
+	ll := centroid.ToLonLat()
+	if !isclose(ll.Lon*180/math.Pi, 19.0/14, 2) || !isclose(ll.Lat*180/math.Pi, 19.0/14, 2) {
+		t.Error(ll.String())
+	}
+	mean, _ := MeanPosition(ring, nil)
+	corner := ring[0]
+	if centroid.SphericalDistance(&corner, 1.0) >= mean.SphericalDistance(&corner, 1.0) {
+		t.Fail()
+	}
+}
+
+func TestPolygonCentroidMatchesTriangulation(t *testing.T) {
+	ring := lonLatRing([][2]float64{{0, 0}, {4, 0}, {4, 1}, {1, 1}, {1, 4}, {0, 4}})
+	centroid, _ := PolygonCentroid(ring)
+
+	// the centroids of the ear-clipped triangles, weighted by their areas
+	triangles, err := Triangulate(ring)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sum Vec3
+	for _, tri := range triangles {
+		c, _ := PolygonCentroid(tri[:])
+		area := SphericalPolygonArea(tri[:], 1)
+		sum = Vec3{sum[0] + area*c.Vec3[0], sum[1] + area*c.Vec3[1], sum[2] + area*c.Vec3[2]}
+	}
+	expected := NVector{normalize(&sum)}
+	if !isclose(centroid.AngleTo(&expected), 0, 6) {
+		t.Error(centroid.AngleTo(&expected))
+	}
+}
+
+func TestPolygonCentroidDegenerate(t *testing.T) {
+	ring := lonLatRing([][2]float64{{0, 0}, {1, 0}, {2, 0}})
+	if _, err := PolygonCentroid(ring); err == nil {
+		t.Fail()
+	}
+}
+
+func TestPolygonIsClockwise(t *testing.T) {
+	ccw := lonLatRing([][2]float64{{-10, -10}, {10, -10}, {10, 10}, {-10, 10}})
+	cw := lonLatRing([][2]float64{{-10, 10}, {10, 10}, {10, -10}, {-10, -10}})
+	if PolygonIsClockwise(ccw) || !PolygonIsClockwise(cw) {
+		t.Fail()
+	}
+}
+
+func TestNormalizeRing(t *testing.T) {
+	// clockwise, unclosed, and with a repeated vertex
+	ring := lonLatRing([][2]float64{{-10, 10}, {10, 10}, {10, 10}, {10, -10}, {-10, -10}})
+	clean := NormalizeRing(ring, true)
+
+	expected := lonLatRing([][2]float64{{-10, -10}, {10, -10}, {10, 10}, {-10, 10}, {-10, -10}})
+	if len(clean) != len(expected) {
+		t.Fatal(len(clean))
+	}
+	for i := range expected {
+		if !isclose(clean[i].SphericalDistance(&expected[i], 1.0), 0, 12) {
+			t.Error(i)
+		}
+	}
+	if PolygonIsClockwise(clean) {
+		t.Fail()
+	}
+
+	// already closed rings are not closed twice
+	again := NormalizeRing(clean, true)
+	if len(again) != len(clean) {
+		t.Fail()
+	}
+
+	cw := NormalizeRing(clean, false)
+	if !PolygonIsClockwise(cw) || len(cw) != len(clean) {
+		t.Fail()
+	}
+}
+
+func TestCircle(t *testing.T) {
+	ll, _ := NewLonLat(-123.1, 49.3)
+	center := ll.ToNVector()
+	R := 6371000.0
+
+	ring := Circle(&center, 50000, R, 72)
+	if len(ring) != 72 {
+		t.Fatal(len(ring))
+	}
+	for i := range ring {
+		if !isclose(center.SphericalDistance(&ring[i], R), 50000, 5) {
+			t.Error(i)
+		}
+	}
+	if !PolygonIsClockwise(ring) {
+		t.Fail()
+	}
+
+	inside := center.Forward(0.3, 49000, R)
+	outside := center.Forward(0.3, 51000, R)
+	if !PointInPolygon(&center, ring) || !PointInPolygon(&inside, ring) || PointInPolygon(&outside, ring) {
+		t.Fail()
+	}
+
+	if Circle(&center, 50000, R, 0) != nil {
+		t.Fail()
+	}
+}
+
+func TestDistanceToPolygon(t *testing.T) {
+	R := 6371e3
+	ring := lonLatRing([][2]float64{{0, 0}, {2, 0}, {2, 2}, {0, 2}})
+
+	inside, _ := NewLonLat(1, 1.9)
+	nvInside := inside.ToNVector()
+	if DistanceToPolygon(&nvInside, ring, R) != 0 {
+		t.Fail()
+	}
+	// the top edge bulges slightly poleward of the parallel at 2 degrees
+	if d := SignedDistanceToPolygon(&nvInside, ring, R); !(d < 0) || math.Abs(d+0.1*math.Pi/180*R) > 100 {
+		t.Error(d)
+	}
+
+	// nearest the closing edge of an open ring
+	outside, _ := NewLonLat(-0.1, 1)
+	nvOutside := outside.ToNVector()
+	expected := 0.1 * math.Pi / 180 * R * math.Cos(math.Pi/180)
+	if !isclose(DistanceToPolygon(&nvOutside, ring, R), expected, 0) {
+		t.Fail()
+	}
+	if !isclose(SignedDistanceToPolygon(&nvOutside, ring, R), expected, 0) {
+		t.Fail()
+	}
+}
+
+func TestConvexHull(t *testing.T) {
+	pts := lonLatRing([][2]float64{
+		{0, 0}, {1, 0.2}, {4, 0}, {2, 1}, {4, 4}, {1.5, 3}, {0, 4}, {2, 2.5}, {0.5, 1},
+	})
+	hull, err := ConvexHull(pts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hull) != 4 {
+		t.Fatal(len(hull))
+	}
+	if PolygonIsClockwise(hull) {
+		t.Error("hull should run counter-clockwise")
+	}
+	// every input point lies within or on the hull
+	for i := range pts {
+		onHull := false
+		for j := range hull {
+			if hull[j] == pts[i] {
+				onHull = true
+			}
+		}
+		if !onHull && !PointInPolygon(&pts[i], hull) {
+			t.Errorf("point %d outside hull", i)
+		}
+	}
+}
+
+func TestConvexHullDegenerate(t *testing.T) {
+	cases := [][][2]float64{
+		{{0, 0}, {1, 1}},
+		{{0, 0}, {1, 0}, {2, 0}, {3, 0}},
+		{{0, 0}, {120, 0}, {-120, 0}, {0, 10}},
+	}
+	for _, c := range cases {
+		if _, err := ConvexHull(lonLatRing(c)); err == nil {
+			t.Errorf("expected error for %v", c)
+		}
+	}
+}
+
+func TestBufferPath(t *testing.T) {
+	R := 6371e3
+	radius := 10e3
+	line := lonLatRing([][2]float64{{0, 0}, {1, 0}, {1.5, 0.5}, {1.5, 1.5}})
+
+	ring := BufferPath(line, radius, R, 16)
+	if ring[0] != ring[len(ring)-1] {
+		t.Error("ring should be closed")
+	}
+	if PolygonIsClockwise(ring) {
+		t.Error("ring should run counter-clockwise")
+	}
+	if crosses, i, j := SelfIntersects(ring); crosses {
+		t.Error("ring crosses itself", i, j)
+	}
+
+	// positions along the path are inside, and those beyond the radius are not
+	for i := 1; i < len(line); i++ {
+		mid := NVector{slerp(&line[i-1].Vec3, &line[i].Vec3, 0.5)}
+		course := bearing(&mid.Vec3, &line[i].Vec3)
+		for _, offset := range []float64{0, 0.9 * radius, -0.9 * radius} {
+			p := mid.OffsetPerpendicular(course, offset, R)
+			if !PointInPolygon(&p, ring) {
+				t.Errorf("segment %d: offset %f should be inside", i, offset)
+			}
+		}
+		for _, offset := range []float64{1.1 * radius, -1.1 * radius} {
+			p := mid.OffsetPerpendicular(course, offset, R)
+			if PointInPolygon(&p, ring) {
+				t.Errorf("segment %d: offset %f should be outside", i, offset)
+			}
+		}
+	}
+	for _, end := range []NVector{line[0], line[len(line)-1]} {
+		if !PointInPolygon(&end, ring) {
+			t.Fail()
+		}
+	}
+
+	// a straight path gives a stadium of area 2rL + pi r^2
+	straight := lonLatRing([][2]float64{{0, 0}, {1, 0}})
+	ring = BufferPath(straight, radius, R, 64)
+	L := straight[0].SphericalDistance(&straight[1], R)
+	area := signedRingArea(ring) * R * R
+	expected := 2*radius*L + math.Pi*radius*radius
+	if math.Abs(area-expected) > 0.005*expected {
+		t.Error(area, expected)
+	}
+}
+
+func TestOffsetStraightCorners(t *testing.T) {
+	R := 6371e3
+	distinct := func(ring []NVector) bool {
+		for i := 1; i < len(ring); i++ {
+			if ring[i-1].AngleTo(&ring[i]) < 1e-9 {
+				return false
+			}
+		}
+		return true
+	}
+
+	// vertices in the middle of straight runs give a single offset point
+	line := lonLatRing([][2]float64{{0, 0}, {0.5, 0}, {1, 0}, {1, 0.5}, {1, 1}})
+	if ring := BufferPath(line, 10e3, R, 8); !distinct(ring) {
+		t.Error("buffer has repeated vertices")
+	}
+	square := lonLatRing([][2]float64{{0, 0}, {0.5, 0}, {1, 0}, {1, 1}, {0, 1}})
+	for _, distance := range []float64{5e3, -5e3} {
+		ring, err := OffsetPolygon(square, distance, R)
+		if err != nil || !distinct(ring) {
+			t.Error(distance, "offset has repeated vertices", err)
+		}
+	}
+}
+
+func TestBufferPathPoint(t *testing.T) {
+	R := 6371e3
+	pos, _ := NewLonLat(5, 5)
+	nv := pos.ToNVector()
+	ring := BufferPath([]NVector{nv, nv}, 1000, R, 8)
+	if len(ring) != 17 || PolygonIsClockwise(ring) {
+		t.Fail()
+	}
+	if !isclose(nv.SphericalDistance(&ring[3], R), 1000, 6) {
+		t.Fail()
+	}
+	if BufferPath(nil, 1000, R, 8) != nil {
+		t.Fail()
+	}
+}
+
+func TestTriangulate(t *testing.T) {
+	// a concave "C" shape, in both winding orders
+	ccw := lonLatRing([][2]float64{{0, 0}, {3, 0}, {3, 1}, {1, 1}, {1, 2}, {3, 2}, {3, 3}, {0, 3}})
+	cw := NormalizeRing(ccw, false)
+	for _, ring := range [][]NVector{ccw, cw} {
+		triangles, err := Triangulate(ring)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(triangles) != 6 {
+			t.Error(len(triangles))
+		}
+		var area float64
+		for _, tri := range triangles {
+			a := signedTriangleArea(&tri[0].Vec3, &tri[1].Vec3, &tri[2].Vec3)
+			if (a > 0) == PolygonIsClockwise(ring) {
+				t.Error("triangle winding differs from ring")
+			}
+			area += a
+		}
+		if !isclose(area, signedRingArea(ring), 12) {
+			t.Error(area, signedRingArea(ring))
+		}
+	}
+}
+
+func TestTriangulateInvalid(t *testing.T) {
+	bowtie := lonLatRing([][2]float64{{0, 0}, {1, 1}, {1, 0}, {0, 1}})
+	if _, err := Triangulate(bowtie); err == nil {
+		t.Fail()
+	}
+	if _, err := Triangulate(bowtie[:2]); err == nil {
+		t.Fail()
+	}
+}
+
+func TestSphericalPolygonArea(t *testing.T) {
+	R := 6371e3
+	// an octant covers an eighth of the sphere
+	octant := lonLatRing([][2]float64{{0, 0}, {90, 0}, {0, 90}})
+	if !isclose(SphericalPolygonArea(octant, R), 0.5*math.Pi*R*R, -3) {
+		t.Fail()
+	}
+	if !isclose(SphericalPolygonArea(NormalizeRing(octant, false), R), 0.5*math.Pi*R*R, -3) {
+		t.Fail()
+	}
+}
+
+// latLonCell returns a ring closely following the parallels and meridians
+// bounding a cell
+func latLonCell(lon0, lat0, lon1, lat1 float64, n int) []NVector {
+	var pts [][2]float64
+	for i := 0; i <= n; i++ {
+		pts = append(pts, [2]float64{lon0 + (lon1-lon0)*float64(i)/float64(n), lat0})
+	}
+	for i := 0; i <= n; i++ {
+		pts = append(pts, [2]float64{lon1 - (lon1-lon0)*float64(i)/float64(n), lat1})
+	}
+	return lonLatRing(pts)
+}
+
+func TestEllipsoidalPolygonArea(t *testing.T) {
+	sphere := Ellipsoid{6371e3, 6371e3}
+	ring := lonLatRing([][2]float64{{0, 0}, {10, 0}, {10, 10}, {0, 10}})
+	if !isclose(EllipsoidalPolygonArea(ring, &sphere), SphericalPolygonArea(ring, 6371e3), -1) {
+		t.Fail()
+	}
+
+	// the area of a cell bounded by parallels and meridians is known exactly
+	// in terms of authalic latitudes
+	wgs84 := Ellipsoid{6378137.0, 6356752.3142}
+	Rq, toAuthalic := wgs84.authalic()
+	if !isclose(Rq, 6371007.18, 1) {
+		t.Error(Rq)
+	}
+	for _, lat0 := range []float64{0, 40, 75} {
+		cell := latLonCell(20, lat0, 22, lat0+1, 200)
+		expected := Rq * Rq * 2 * math.Pi / 180 *
+			(math.Sin(toAuthalic((lat0+1)*math.Pi/180)) - math.Sin(toAuthalic(lat0*math.Pi/180)))
+		area := EllipsoidalPolygonArea(cell, &wgs84)
+		if math.Abs(area-expected) > 1e-5*expected {
+			t.Error(lat0, area, expected)
+		}
+	}
+}
+
+func TestOffsetPolygon(t *testing.T) {
+	R := 6371e3
+	square := lonLatRing([][2]float64{{0, 0}, {1, 0}, {1, 1}, {0, 1}})
+	side := square[0].SphericalDistance(&square[1], R)
+	centre := lonLatRing([][2]float64{{0.5, 0.5}})[0]
+
+	for _, distance := range []float64{5e3, -5e3} {
+		ring, err := OffsetPolygon(square, distance, R)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ring[0] != ring[len(ring)-1] || PolygonIsClockwise(ring) {
+			t.Error("ring should be closed and counter-clockwise")
+		}
+		if !PointInPolygon(&centre, ring) {
+			t.Error(distance, "centre should be inside")
+		}
+		for i := range square {
+			if PointInPolygon(&square[i], ring) != (distance > 0) {
+				t.Error(distance, "vertex", i)
+			}
+		}
+		// the area changes by the perimeter times the distance, plus rounded
+		// corners outside or less mitred corners inside
+		delta := (signedRingArea(ring) - signedRingArea(square)) * R * R
+		expected := 4*side*distance + math.Pi*distance*distance
+		if distance < 0 {
+			expected = 4*side*distance + 4*distance*distance
+		}
+		if math.Abs(delta-expected) > 0.005*math.Abs(expected) {
+			t.Error(distance, delta, expected)
+		}
+	}
+
+	// the winding order of the input doesn't matter
+	reversed := NormalizeRing(square, false)
+	ring, err := OffsetPolygon(reversed, 5e3, R)
+	if err != nil || !PointInPolygon(&square[0], ring) {
+		t.Error("offset of clockwise ring", err)
+	}
+}
+
+func TestOffsetPolygonLarge(t *testing.T) {
+	R := 6371e3
+	shapes := [][]NVector{
+		lonLatRing([][2]float64{{0, -5}, {10, -5}, {10, 5}, {0, 5}}),
+		lonLatRing([][2]float64{{-10, 40}, {20, 40}, {20, 60}, {-10, 60}}),
+		lonLatRing([][2]float64{{0, 0}, {20, 0}, {10, 15}}),
+	}
+	for i, shape := range shapes {
+		for _, distance := range []float64{-200e3, -50e3, 50e3, 200e3} {
+			ring, err := OffsetPolygon(shape, distance, R)
+			if err != nil {
+				t.Error(i, distance, err)
+				continue
+			}
+			// every vertex of the offset lies *distance* or more from the
+			// original boundary, on the side given by its sign
+			for j := range ring {
+				d := SignedDistanceToPolygon(&ring[j], shape, R)
+				if d*math.Copysign(1, distance) < math.Abs(distance)-1e-3 {
+					t.Error(i, distance, j, d)
+				}
+			}
+			if area := signedRingArea(ring); (area < signedRingArea(NormalizeRing(shape, true))) != (distance < 0) {
+				t.Error(i, distance, "area changes the wrong way")
+			}
+		}
+	}
+}
+
+func TestOffsetPolygonDegenerate(t *testing.T) {
+	R := 6371e3
+	square := lonLatRing([][2]float64{{0, 0}, {1, 0}, {1, 1}, {0, 1}})
+	if _, err := OffsetPolygon(square, -100e3, R); err == nil {
+		t.Error("inward offset wider than the polygon should fail")
+	}
+
+	// a narrow notch pinches closed under an inward offset
+	notched := lonLatRing([][2]float64{{0, 0}, {2, 0}, {2, 1}, {1.05, 1}, {1.05, 0.1},
+		{0.95, 0.1}, {0.95, 1}, {0, 1}})
+	if _, err := OffsetPolygon(notched, -10e3, R); err == nil {
+		t.Error("inward offset of a pinched polygon should fail")
+	}
+	if _, err := OffsetPolygon(notched, 2e3, R); err != nil {
+		t.Error(err)
+	}
+
+	if _, err := OffsetPolygon(square[:2], 1e3, R); err == nil {
+		t.Fail()
+	}
+}
@@ -0,0 +1,93 @@
+package nvector
+
+import (
+	"math"
+	"strings"
+)
+
+// EncodePolyline returns the positions *pts* in Google's encoded polyline
+// format, with coordinates rounded to *precision* decimal places of a degree.
+// The standard format uses a precision of 5, and some services (e.g. OSRM and
+// Valhalla) use 6. Each position is encoded as its latitude and longitude
+// differences from the one before.
+func EncodePolyline(pts []LonLat, precision int) string {
+	scale := math.Pow10(precision)
+	var enc strings.Builder
+	var prevLat, prevLon int64
+	for i := range pts {
+		lat := int64(math.Round(pts[i].Lat * 180.0 / math.Pi * scale))
+		lon := int64(math.Round(wrapAngle(pts[i].Lon) * 180.0 / math.Pi * scale))
+		encodePolylineValue(&enc, lat-prevLat)
+		encodePolylineValue(&enc, lon-prevLon)
+		prevLat, prevLon = lat, lon
+	}
+	return enc.String()
+}
+
+// encodePolylineValue appends the signed value *v* to *enc* as a sequence of
+// five-bit chunks, least significant first, each offset into printable ASCII
+func encodePolylineValue(enc *strings.Builder, v int64) {
+	u := uint64(v) << 1
+	if v < 0 {
+		u = ^u
+	}
+	for u >= 0x20 {
+		enc.WriteByte(byte(0x20|(u&0x1f)) + 63)
+		u >>= 5
+	}
+	enc.WriteByte(byte(u) + 63)
+}
+
+// DecodePolyline returns the positions in the Google encoded polyline *s*,
+// with coordinates given to *precision* decimal places of a degree, as in
+// EncodePolyline. ParseError is returned if the polyline contains characters
+// outside the format or ends partway through a position, and
+// InvalidLatitudeError if a decoded latitude falls outside [-90, 90], which
+// usually means that the wrong precision was given.
+func DecodePolyline(s string, precision int) ([]LonLat, error) {
+	scale := math.Pow10(precision)
+	var pts []LonLat
+	var lat, lon int64
+	for pos := 0; pos < len(s); {
+		var dlat, dlon int64
+		var ok bool
+		if dlat, pos, ok = decodePolylineValue(s, pos); !ok {
+			return nil, ParseError{s}
+		}
+		if dlon, pos, ok = decodePolylineValue(s, pos); !ok {
+			return nil, ParseError{s}
+		}
+		lat += dlat
+		lon += dlon
+		ll, err := NewLonLat(float64(lon)/scale, float64(lat)/scale)
+		if err != nil {
+			return nil, err
+		}
+		pts = append(pts, *ll)
+	}
+	return pts, nil
+}
+
+// decodePolylineValue reads the signed value starting at *pos* in *s*,
+// returning it with the position following it, or false if the value is
+// malformed or truncated
+func decodePolylineValue(s string, pos int) (int64, int, bool) {
+	var u uint64
+	for shift := uint(0); pos < len(s) && shift < 64; shift += 5 {
+		c := s[pos]
+		pos++
+		if c < 63 || c > 126 {
+			return 0, pos, false
+		}
+		chunk := uint64(c - 63)
+		u |= (chunk & 0x1f) << shift
+		if chunk < 0x20 {
+			v := int64(u >> 1)
+			if u&1 != 0 {
+				v = ^v
+			}
+			return v, pos, true
+		}
+	}
+	return 0, pos, false
+}
@@ -0,0 +1,76 @@
+package nvector
+
+import (
+	"math"
+	"testing"
+)
+
+func TestEncodePolyline(t *testing.T) {
+	// the example from Google's description of the format
+	pts := make([]LonLat, 3)
+	for i, c := range [][2]float64{{-120.2, 38.5}, {-120.95, 40.7}, {-126.453, 43.252}} {
+		ll, _ := NewLonLat(c[0], c[1])
+		pts[i] = *ll
+	}
+	if s := EncodePolyline(pts, 5); s != "_p~iF~ps|U_ulLnnqC_mqNvxq`@" {
+		t.Error(s)
+	}
+
+	if s := EncodePolyline(nil, 5); s != "" {
+		t.Error(s)
+	}
+}
+
+func TestDecodePolyline(t *testing.T) {
+	pts, err := DecodePolyline("_p~iF~ps|U_ulLnnqC_mqNvxq`@", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := [][2]float64{{-120.2, 38.5}, {-120.95, 40.7}, {-126.453, 43.252}}
+	if len(pts) != len(expected) {
+		t.Fatal(len(pts))
+	}
+	for i, c := range expected {
+		if !isclose(pts[i].Lon*180/math.Pi, c[0], 9) || !isclose(pts[i].Lat*180/math.Pi, c[1], 9) {
+			t.Error(i, pts[i])
+		}
+	}
+}
+
+func TestPolylineRoundTrip(t *testing.T) {
+	var pts []LonLat
+	for _, c := range [][2]float64{{-179.999999, -89.5}, {0, 0}, {179.123456, 89.999999}, {-0.000001, 0.000001}} {
+		ll, _ := NewLonLat(c[0], c[1])
+		pts = append(pts, *ll)
+	}
+	for _, precision := range []int{5, 6} {
+		decoded, err := DecodePolyline(EncodePolyline(pts, precision), precision)
+		if err != nil || len(decoded) != len(pts) {
+			t.Fatal(precision, err)
+		}
+		tol := 0.5 * math.Pow10(-precision) * math.Pi / 180
+		for i := range pts {
+			if math.Abs(wrapAngle(decoded[i].Lon-pts[i].Lon)) > tol || math.Abs(decoded[i].Lat-pts[i].Lat) > tol {
+				t.Error(precision, i, decoded[i], pts[i])
+			}
+		}
+	}
+}
+
+func TestDecodePolylineInvalid(t *testing.T) {
+	// truncated partway through a position, and outside the alphabet
+	for _, s := range []string{"_p~iF", "_p~iF~ps|", "_p~iF ~ps|U"} {
+		if _, err := DecodePolyline(s, 5); err == nil {
+			t.Error(s)
+		}
+	}
+
+	// a precision-6 polyline read with precision 5 has latitudes out of range
+	ll, _ := NewLonLat(10, 50)
+	s := EncodePolyline([]LonLat{*ll}, 6)
+	if _, err := DecodePolyline(s, 5); err == nil {
+		t.Error("expected an invalid latitude")
+	} else if _, ok := err.(InvalidLatitudeError); !ok {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,182 @@
+package nvector
+
+import (
+	"fmt"
+	"math"
+)
+
+// ProjectionDomainError is returned for positions that a projection can't
+// represent. Angle is the angular distance in radians from the projection
+// centre.
+type ProjectionDomainError struct {
+	Angle float64
+}
+
+func (e ProjectionDomainError) Error() string {
+	return fmt.Sprintf("position %f radians from the centre is outside the projection", e.Angle)
+}
+
+// Projection names understood by Ellipsoid.LocalScale. These name the
+// projections for which a closed-form scale is known; for the Projection
+// types, use LonLat.ScaleFactor.
+const (
+	ProjMercator           = "mercator"
+	ProjTransverseMercator = "transverse mercator"
+)
+
+// LocalScale returns the point scale factor of the named projection at *ll*,
+// i.e. the ratio of a short distance measured on the map to the same distance
+// on the ellipsoid. For transverse Mercator, the longitude of *ll* is taken
+// relative to the central meridian and the central scale factor is 1, which
+// matches ScaleFactor for a TransverseMercator with a Scale of 1. NaN is
+// returned for unrecognized projections.
+func (ellps *Ellipsoid) LocalScale(ll LonLat, projection string) float64 {
+	e2 := 1 - ellps.b*ellps.b/(ellps.a*ellps.a)
+	sinlat := math.Sin(ll.Lat)
+	coslat := math.Cos(ll.Lat)
+
+	switch projection {
+	case ProjMercator:
+		return math.Sqrt(1-e2*sinlat*sinlat) / coslat
+	case ProjTransverseMercator:
+		ep2 := e2 / (1 - e2)
+		T := math.Tan(ll.Lat) * math.Tan(ll.Lat)
+		C := ep2 * coslat * coslat
+		A := coslat * ll.Lon
+		A2 := A * A
+		return 1 + (1+C)*A2/2 +
+			(5-4*T+42*C+13*C*C-28*ep2)*A2*A2/24 +
+			(61-148*T+16*T*T)*A2*A2*A2/720
+	}
+	return math.NaN()
+}
+
+// webMercatorRadius is the radius of the sphere used by Web Mercator
+// (EPSG:3857), equal to the WGS84 semi-major axis
+const webMercatorRadius = 6378137.0
+
+// webMercatorMaxLat is the latitude at which Web Mercator maps become square,
+// about 85.0511⁰
+var webMercatorMaxLat = math.Atan(math.Sinh(math.Pi))
+
+// ToWebMercator returns the Web Mercator (EPSG:3857) coordinates of the
+// position in meters, using the spherical Mercator formulas. Latitudes beyond
+// the Web Mercator limit of about ±85.0511⁰ are clamped to it, so that the
+// coordinates remain finite.
+func (ll *LonLat) ToWebMercator() (x, y float64) {
+	lat := math.Max(-webMercatorMaxLat, math.Min(webMercatorMaxLat, ll.Lat))
+	x = webMercatorRadius * ll.Lon
+	y = webMercatorRadius * math.Log(math.Tan(0.25*math.Pi+0.5*lat))
+	return x, y
+}
+
+// FromWebMercator returns the position with the given Web Mercator
+// (EPSG:3857) coordinates in meters
+func FromWebMercator(x, y float64) LonLat {
+	lon := x / webMercatorRadius
+	lat := 2*math.Atan(math.Exp(y/webMercatorRadius)) - 0.5*math.Pi
+	return LonLat{lon, lat}
+}
+
+// Gnomonic returns the coordinates of *pt* in the gnomonic projection
+// centered on *center*, on the plane tangent to the unit sphere there, with x
+// increasing east and y north. Great circles project to straight lines, so
+// problems such as segment intersection reduce to planar geometry. Multiply
+// by the sphere radius for distances in meters near the centre.
+// ProjectionDomainError is returned for points 90⁰ or more from the centre.
+func (center *NVector) Gnomonic(pt *NVector) (x, y float64, err error) {
+	c := normalize(&center.Vec3)
+	p := normalize(&pt.Vec3)
+	d := dot(&p, &c)
+	if d <= 1e-12 {
+		return 0, 0, ProjectionDomainError{center.AngleTo(pt)}
+	}
+	north, east := northEast(&c)
+	return dot(&p, &east) / d, dot(&p, &north) / d, nil
+}
+
+// GnomonicInverse returns the position with coordinates *x* and *y* in the
+// gnomonic projection centered on *center*, reversing Gnomonic
+func (center *NVector) GnomonicInverse(x, y float64) NVector {
+	c := normalize(&center.Vec3)
+	north, east := northEast(&c)
+	p := Vec3{c[0] + x*east[0] + y*north[0],
+		c[1] + x*east[1] + y*north[1],
+		c[2] + x*east[2] + y*north[2]}
+	return NVector{normalize(&p)}
+}
+
+// Projection is a map projection of positions on an ellipsoid to plane
+// coordinates in meters. Its distortion is given by LonLat.ScaleFactor, which
+// generalizes Ellipsoid.LocalScale to any projection.
+type Projection interface {
+	// Project returns the plane coordinates of *ll*
+	Project(ll LonLat) (x, y float64)
+	// Ellipsoid returns the ellipsoid that positions are taken to lie on
+	Ellipsoid() *Ellipsoid
+}
+
+// WebMercator is the Web Mercator (EPSG:3857) projection, on a sphere with the
+// WGS84 semi-major axis as its radius
+type WebMercator struct{}
+
+// Project returns the Web Mercator coordinates of *ll*, as ToWebMercator
+func (WebMercator) Project(ll LonLat) (x, y float64) {
+	return ll.ToWebMercator()
+}
+
+// Ellipsoid returns the sphere that Web Mercator takes positions to lie on
+func (WebMercator) Ellipsoid() *Ellipsoid {
+	return &Ellipsoid{webMercatorRadius, webMercatorRadius}
+}
+
+// TransverseMercator is the transverse Mercator projection of *Ellps* about
+// the meridian at longitude CentralMeridian in radians, with scale factor
+// Scale along it. A UTM zone has a central meridian of (6*zone-183)⁰ and a
+// scale of 0.9996, omitting the false easting and northing.
+type TransverseMercator struct {
+	CentralMeridian float64
+	Scale           float64
+	Ellps           *Ellipsoid
+}
+
+// Project returns the transverse Mercator coordinates of *ll*, with x east of
+// the central meridian and y north of the equator
+func (tm TransverseMercator) Project(ll LonLat) (x, y float64) {
+	x, y = gaussKruger(ll.Lat, wrapAngle(ll.Lon-tm.CentralMeridian), tm.Ellps)
+	return tm.Scale * x, tm.Scale * y
+}
+
+// Ellipsoid returns the ellipsoid being projected, Ellps
+func (tm TransverseMercator) Ellipsoid() *Ellipsoid {
+	return tm.Ellps
+}
+
+// ScaleFactor returns the scale of the projection *proj* at the position
+// along the meridian, *h*, and along the parallel, *k*, and the areal scale,
+// which is the ratio of a small area on the map to the same area on the
+// ellipsoid. A conformal projection has equal *h* and *k*, and an equal-area
+// projection has unit areal scale. The derivatives of the projection are
+// estimated by central differences. The parallel scale is undefined, and NaN
+// returned, at the poles.
+func (ll *LonLat) ScaleFactor(proj Projection) (h, k, area float64) {
+	const step = 1e-5
+	ellps := proj.Ellipsoid()
+	M := ellps.MeridianRadius(ll.Lat)
+	Ncoslat := ellps.PrimeVerticalRadius(ll.Lat) * math.Cos(ll.Lat)
+	if math.Abs(Ncoslat) < 1e-9 {
+		return math.NaN(), math.NaN(), math.NaN()
+	}
+
+	xn, yn := proj.Project(LonLat{ll.Lon, ll.Lat + step})
+	xs, ys := proj.Project(LonLat{ll.Lon, ll.Lat - step})
+	xe, ye := proj.Project(LonLat{ll.Lon + step, ll.Lat})
+	xw, yw := proj.Project(LonLat{ll.Lon - step, ll.Lat})
+	dxdlat, dydlat := (xn-xs)/(2*step), (yn-ys)/(2*step)
+	dxdlon, dydlon := (xe-xw)/(2*step), (ye-yw)/(2*step)
+
+	h = math.Hypot(dxdlat, dydlat) / M
+	k = math.Hypot(dxdlon, dydlon) / Ncoslat
+	area = math.Abs(dxdlon*dydlat-dxdlat*dydlon) / (M * Ncoslat)
+	return h, k, area
+}
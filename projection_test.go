@@ -0,0 +1,187 @@
+package nvector
+
+import (
+	"math"
+	"testing"
+)
+
+func TestLocalScaleMercator(t *testing.T) {
+	sphere := Ellipsoid{6370997.0, 6370997.0}
+	for _, latdeg := range []float64{0, 15, 30, 45, 60, 75} {
+		ll, _ := NewLonLat(20, latdeg)
+		k := sphere.LocalScale(*ll, ProjMercator)
+		if !isclose(k, 1/math.Cos(ll.Lat), 10) {
+			t.Error(latdeg, k)
+		}
+	}
+
+	// flattening reduces the scale slightly away from the equator
+	wgs84 := Ellipsoid{6378137.0, 6356752.3142}
+	ll, _ := NewLonLat(0, 0)
+	if !isclose(wgs84.LocalScale(*ll, ProjMercator), 1, 12) {
+		t.Fail()
+	}
+	ll, _ = NewLonLat(0, 60)
+	k := wgs84.LocalScale(*ll, ProjMercator)
+	if !(k < 2) || !(k > 1.99) {
+		t.Error(k)
+	}
+}
+
+func TestLocalScaleTransverseMercator(t *testing.T) {
+	wgs84 := Ellipsoid{6378137.0, 6356752.3142}
+
+	// unity along the central meridian
+	ll, _ := NewLonLat(0, 45)
+	if !isclose(wgs84.LocalScale(*ll, ProjTransverseMercator), 1, 12) {
+		t.Fail()
+	}
+
+	// 3 degrees off the central meridian at the equator: about 1.00137,
+	// which becomes 0.99997 after applying the UTM factor of 0.9996
+	ll, _ = NewLonLat(3, 0)
+	k := wgs84.LocalScale(*ll, ProjTransverseMercator)
+	if !isclose(k, 1.001373, 5) {
+		t.Error(k)
+	}
+}
+
+func TestLocalScaleUnknown(t *testing.T) {
+	wgs84 := Ellipsoid{6378137.0, 6356752.3142}
+	ll, _ := NewLonLat(0, 45)
+	if !math.IsNaN(wgs84.LocalScale(*ll, "gnomonic")) {
+		t.Fail()
+	}
+}
+
+func TestToWebMercator(t *testing.T) {
+	ll, _ := NewLonLat(0, 0)
+	x, y := ll.ToWebMercator()
+	if !isclose(x, 0, 9) || !isclose(y, 0, 9) {
+		t.Fail()
+	}
+
+	// the limiting latitude maps to the edge of the square world
+	ll, _ = NewLonLat(-180, 85.0511287798)
+	x, y = ll.ToWebMercator()
+	if !isclose(x, -20037508.342789244, 4) || !isclose(y, 20037508.342789244, 2) {
+		t.Error(x, y)
+	}
+
+	// latitudes beyond the limit are clamped
+	ll, _ = NewLonLat(10, -90)
+	_, y = ll.ToWebMercator()
+	if math.IsInf(y, 0) || !isclose(y, -20037508.342789244, 4) {
+		t.Error(y)
+	}
+}
+
+func TestFromWebMercator(t *testing.T) {
+	ll, _ := NewLonLat(-122.4194, 37.7749)
+	x, y := ll.ToWebMercator()
+	ll2 := FromWebMercator(x, y)
+	if !isclose(ll2.Lon, ll.Lon, 12) || !isclose(ll2.Lat, ll.Lat, 12) {
+		t.Fail()
+	}
+	if !isclose(x, -13627665.27, 1) || !isclose(y, 4547675.35, 1) {
+		t.Error(x, y)
+	}
+}
+
+func TestGnomonic(t *testing.T) {
+	llc, _ := NewLonLat(-123, 49)
+	center := llc.ToNVector()
+	x, y, err := center.Gnomonic(&center)
+	if err != nil || !isclose(x, 0, 12) || !isclose(y, 0, 12) {
+		t.Fail()
+	}
+
+	// points north and east of the centre project onto the positive axes
+	north := center.Forward(0, 100e3, 6371e3)
+	x, y, _ = center.Gnomonic(&north)
+	if !isclose(x, 0, 9) || !isclose(y, math.Tan(100e3/6371e3), 9) {
+		t.Error(x, y)
+	}
+	east := center.Forward(0.5*math.Pi, 100e3, 6371e3)
+	x, y, _ = center.Gnomonic(&east)
+	if !(x > 0) || !isclose(y, 0, 9) {
+		t.Error(x, y)
+	}
+
+	// round trip
+	ll, _ := NewLonLat(-100, 30)
+	nv := ll.ToNVector()
+	x, y, err = center.Gnomonic(&nv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	back := center.GnomonicInverse(x, y)
+	if !isclose(back.AngleTo(&nv), 0, 12) {
+		t.Fail()
+	}
+
+	// great circles project to straight lines
+	ll1, _ := NewLonLat(-130, 40)
+	ll2, _ := NewLonLat(-110, 55)
+	nv1, nv2 := ll1.ToNVector(), ll2.ToNVector()
+	x1, y1, _ := center.Gnomonic(&nv1)
+	x2, y2, _ := center.Gnomonic(&nv2)
+	for _, frac := range []float64{0.2, 0.5, 0.9} {
+		mid := NVector{slerp(&nv1.Vec3, &nv2.Vec3, frac)}
+		xm, ym, _ := center.Gnomonic(&mid)
+		if !isclose((x2-x1)*(ym-y1)-(y2-y1)*(xm-x1), 0, 12) {
+			t.Error(frac)
+		}
+	}
+}
+
+func TestGnomonicOutOfDomain(t *testing.T) {
+	llc, _ := NewLonLat(0, 0)
+	center := llc.ToNVector()
+	for _, londeg := range []float64{90, 135, 180} {
+		ll, _ := NewLonLat(londeg, 0)
+		nv := ll.ToNVector()
+		_, _, err := center.Gnomonic(&nv)
+		if _, ok := err.(ProjectionDomainError); !ok {
+			t.Error(londeg, err)
+		}
+	}
+}
+
+func TestScaleFactorWebMercator(t *testing.T) {
+	for _, latdeg := range []float64{0, 30, 60} {
+		ll, _ := NewLonLat(-75, latdeg)
+		h, k, area := ll.ScaleFactor(WebMercator{})
+		sec := 1 / math.Cos(ll.Lat)
+		if !isclose(h, sec, 8) || !isclose(k, sec, 8) || !isclose(area, sec*sec, 7) {
+			t.Error(latdeg, h, k, area)
+		}
+	}
+}
+
+func TestScaleFactorTransverseMercator(t *testing.T) {
+	wgs84 := Ellipsoid{6378137.0, 6356752.3142}
+	utm10 := TransverseMercator{-123 * math.Pi / 180, 0.9996, &wgs84}
+
+	ll, _ := NewLonLat(-121.5, 49)
+	x, y := utm10.Project(*ll)
+	easting, northing, _, _, _ := ll.ToUTM(&wgs84)
+	if !isclose(x+500000, easting, 6) || !isclose(y, northing, 6) {
+		t.Error(x, y)
+	}
+
+	ll, _ = NewLonLat(-123, 49)
+	h, k, area := ll.ScaleFactor(utm10)
+	if !isclose(h, 0.9996, 8) || !isclose(k, 0.9996, 8) || !isclose(area, 0.9996*0.9996, 8) {
+		t.Error(h, k, area)
+	}
+
+	// conformal away from the central meridian, matching the series scale
+	ll, _ = NewLonLat(-120, 49)
+	h, k, _ = ll.ScaleFactor(utm10)
+	offset, _ := NewLonLat(3, 49)
+	expected := 0.9996 * wgs84.LocalScale(*offset, ProjTransverseMercator)
+	if !isclose(h, k, 8) || !isclose(k, expected, 6) {
+		t.Error(h, k, expected)
+	}
+}
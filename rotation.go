@@ -0,0 +1,219 @@
+package nvector
+
+import "math"
+
+// MultMatrix returns the matrix product of *m* and *m2*, which applies *m2*
+// followed by *m*.
+func (m *Matrix3) MultMatrix(m2 *Matrix3) Matrix3 {
+	var p Matrix3
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			p[i][j] = m[i][0]*m2[0][j] + m[i][1]*m2[1][j] + m[i][2]*m2[2][j]
+		}
+	}
+	return p
+}
+
+// IsOrthonormal returns whether the rows of *m* are unit length and mutually
+// perpendicular, with no element of m*mᵀ differing from the identity by more
+// than *tol*
+func (m *Matrix3) IsOrthonormal(tol float64) bool {
+	mt := m.Transpose()
+	p := m.MultMatrix(&mt)
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			expected := 0.0
+			if i == j {
+				expected = 1
+			}
+			if math.Abs(p[i][j]-expected) > tol {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Orthonormalize returns the orthonormal matrix nearest to *m*, which for a
+// rotation matrix that has drifted through rounding is the rotation it
+// approximates. It is the orthogonal factor of the polar decomposition, found
+// by Newton's iteration X = (X + X⁻ᵀ)/2, which unlike Gram-Schmidt treats all
+// axes alike. The zero matrix is returned if *m* is singular.
+func (m *Matrix3) Orthonormalize() Matrix3 {
+	x := *m
+	for i := 0; i < 50; i++ {
+		// the inverse transpose is the cofactor matrix over the determinant
+		c0 := cross((*Vec3)(&x[1]), (*Vec3)(&x[2]))
+		c1 := cross((*Vec3)(&x[2]), (*Vec3)(&x[0]))
+		c2 := cross((*Vec3)(&x[0]), (*Vec3)(&x[1]))
+		det := dot((*Vec3)(&x[0]), &c0)
+		if det == 0 || math.IsNaN(det) {
+			return Matrix3{}
+		}
+		var next Matrix3
+		var change float64
+		for j, c := range []Vec3{c0, c1, c2} {
+			for k := 0; k < 3; k++ {
+				next[j][k] = 0.5 * (x[j][k] + c[k]/det)
+				change = math.Max(change, math.Abs(next[j][k]-x[j][k]))
+			}
+		}
+		x = next
+		if change < 1e-15 {
+			break
+		}
+	}
+	return x
+}
+
+// RotationAboutAxis returns the matrix rotating vectors by *angle* radians
+// counter-clockwise about *axis*, following the right-hand rule.
+func RotationAboutAxis(axis Vec3, angle float64) Matrix3 {
+	k := normalize(&axis)
+	c := math.Cos(angle)
+	s := math.Sin(angle)
+	t := 1 - c
+	return Matrix3{
+		[3]float64{c + t*k[0]*k[0], t*k[0]*k[1] - s*k[2], t*k[0]*k[2] + s*k[1]},
+		[3]float64{t*k[1]*k[0] + s*k[2], c + t*k[1]*k[1], t*k[1]*k[2] - s*k[0]},
+		[3]float64{t*k[2]*k[0] - s*k[1], t*k[2]*k[1] + s*k[0], c + t*k[2]*k[2]},
+	}
+}
+
+// RotationBetween returns the smallest rotation taking *nv* to *nv2*, about
+// the normal of the great circle through them. Antipodal positions are
+// related by a half turn about an arbitrary perpendicular axis.
+func RotationBetween(nv, nv2 *NVector) Matrix3 {
+	a := normalize(&nv.Vec3)
+	b := normalize(&nv2.Vec3)
+	axis := cross(&a, &b)
+	angle := math.Atan2(axis.Magnitude(), dot(&a, &b))
+
+	if axis.Magnitude() < 1e-15 {
+		if angle < 0.5*math.Pi {
+			return Matrix3{[3]float64{1, 0, 0}, [3]float64{0, 1, 0}, [3]float64{0, 0, 1}}
+		}
+		axis = cross(&a, &Vec3{1, 0, 0})
+		if axis.Magnitude() < 1e-6 {
+			axis = cross(&a, &Vec3{0, 1, 0})
+		}
+	}
+	return RotationAboutAxis(axis, angle)
+}
+
+// AlignToNorth rotates *track* rigidly so that it starts at 0⁰N 0⁰E, well
+// away from the poles, heading due north. This removes the absolute position
+// and orientation of a track so that tracks can be compared by shape alone.
+// The rotation applied is also returned.
+func AlignToNorth(track []NVector) ([]NVector, Matrix3) {
+	rot := Matrix3{[3]float64{1, 0, 0}, [3]float64{0, 1, 0}, [3]float64{0, 0, 1}}
+	if len(track) == 0 {
+		return nil, rot
+	}
+
+	origin := NVector{Vec3{1, 0, 0}}
+	rot = RotationBetween(&track[0], &origin)
+	if len(track) > 1 {
+		next := rot.Mult(&track[1].Vec3)
+		heading := RotationAboutAxis(origin.Vec3, bearing(&origin.Vec3, &next))
+		rot = heading.MultMatrix(&rot)
+	}
+
+	aligned := make([]NVector, len(track))
+	for i := range track {
+		aligned[i] = NVector{rot.Mult(&track[i].Vec3)}
+	}
+	return aligned, rot
+}
+
+// LocalFrame is the North-East-Down frame at Origin. R_EN is the rotation
+// matrix from RotationMatrix, which takes NED vectors to Earth-centered
+// vectors.
+type LocalFrame struct {
+	Origin NVector
+	R_EN   Matrix3
+}
+
+// LocalFrame returns the North-East-Down frame at *nv*
+func (nv *NVector) LocalFrame() LocalFrame {
+	return LocalFrame{*nv, nv.RotationMatrix()}
+}
+
+// ECEFtoLocal returns the North-East-Down components of the Earth-centered
+// vector *v*
+func (f *LocalFrame) ECEFtoLocal(v Vec3) Vec3 {
+	r_NE := f.R_EN.Transpose()
+	return r_NE.Mult(&v)
+}
+
+// LocalToECEF returns the Earth-centered components of the North-East-Down
+// vector *v*
+func (f *LocalFrame) LocalToECEF(v Vec3) Vec3 {
+	return f.R_EN.Mult(&v)
+}
+
+// RotationQuaternion returns the unit quaternion equivalent to the rotation
+// matrix from RotationMatrix, as [w, x, y, z] with the scalar part first and
+// w non-negative
+func (nv *NVector) RotationQuaternion() [4]float64 {
+	m := nv.RotationMatrix()
+	return matrixToQuaternion(&m)
+}
+
+// matrixToQuaternion converts a rotation matrix to a unit quaternion using
+// Shepperd's method, which divides by the largest available quantity to
+// remain stable for all rotations
+func matrixToQuaternion(m *Matrix3) [4]float64 {
+	var q [4]float64
+	trace := m[0][0] + m[1][1] + m[2][2]
+	switch {
+	case trace >= m[0][0] && trace >= m[1][1] && trace >= m[2][2]:
+		s := 2 * math.Sqrt(1+trace)
+		q = [4]float64{0.25 * s, (m[2][1] - m[1][2]) / s, (m[0][2] - m[2][0]) / s, (m[1][0] - m[0][1]) / s}
+	case m[0][0] >= m[1][1] && m[0][0] >= m[2][2]:
+		s := 2 * math.Sqrt(1+m[0][0]-m[1][1]-m[2][2])
+		q = [4]float64{(m[2][1] - m[1][2]) / s, 0.25 * s, (m[0][1] + m[1][0]) / s, (m[0][2] + m[2][0]) / s}
+	case m[1][1] >= m[2][2]:
+		s := 2 * math.Sqrt(1+m[1][1]-m[0][0]-m[2][2])
+		q = [4]float64{(m[0][2] - m[2][0]) / s, (m[0][1] + m[1][0]) / s, 0.25 * s, (m[1][2] + m[2][1]) / s}
+	default:
+		s := 2 * math.Sqrt(1+m[2][2]-m[0][0]-m[1][1])
+		q = [4]float64{(m[1][0] - m[0][1]) / s, (m[0][2] + m[2][0]) / s, (m[1][2] + m[2][1]) / s, 0.25 * s}
+	}
+	if q[0] < 0 {
+		q = [4]float64{-q[0], -q[1], -q[2], -q[3]}
+	}
+	return q
+}
+
+// QuaternionToMatrix returns the rotation matrix equivalent to the unit
+// quaternion *q*, given as [w, x, y, z] with the scalar part first
+func QuaternionToMatrix(q [4]float64) Matrix3 {
+	w, x, y, z := q[0], q[1], q[2], q[3]
+	return Matrix3{
+		[3]float64{1 - 2*(y*y+z*z), 2 * (x*y - w*z), 2 * (x*z + w*y)},
+		[3]float64{2 * (x*y + w*z), 1 - 2*(x*x+z*z), 2 * (y*z - w*x)},
+		[3]float64{2 * (x*z - w*y), 2 * (y*z + w*x), 1 - 2*(x*x+y*y)},
+	}
+}
+
+// EulerAngles returns the aerospace (z-y'-x'', or 3-2-1) Euler angles of the
+// rotation *m*, such that *m* is the product of a rotation by *yaw* about z,
+// then by *pitch* about the new y axis, then by *roll* about the new x axis.
+// Applied to the matrix from RotationMatrix, these are the attitude angles of
+// a body whose axes are aligned with the local north, east, and down. Roll
+// and yaw are in (-pi, pi] and pitch in [-pi/2, pi/2]. At pitch = ±pi/2 roll
+// and yaw rotate about the same axis and only their combination is
+// determined, so roll is taken as zero.
+func (m *Matrix3) EulerAngles() (roll, pitch, yaw float64) {
+	sinPitch := -m[2][0]
+	if math.Abs(sinPitch) >= 1-1e-12 {
+		pitch = math.Copysign(0.5*math.Pi, sinPitch)
+		yaw = math.Atan2(-m[0][1], m[1][1])
+		return 0, pitch, yaw
+	}
+	roll = math.Atan2(m[2][1], m[2][2])
+	pitch = math.Asin(sinPitch)
+	yaw = math.Atan2(m[1][0], m[0][0])
+	return roll, pitch, yaw
+}
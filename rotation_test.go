@@ -0,0 +1,222 @@
+package nvector
+
+import (
+	"math"
+	"testing"
+)
+
+func TestMultMatrix(t *testing.T) {
+	m1 := Matrix3{[3]float64{1, 2, 0}, [3]float64{0, 1, 0}, [3]float64{0, 0, 2}}
+	m2 := Matrix3{[3]float64{0, 1, 0}, [3]float64{1, 0, 0}, [3]float64{0, 0, 1}}
+	p := m1.MultMatrix(&m2)
+	if (p != Matrix3{[3]float64{2, 1, 0}, [3]float64{1, 0, 0}, [3]float64{0, 0, 2}}) {
+		t.Fail()
+	}
+}
+
+func TestOrthonormalize(t *testing.T) {
+	ll, _ := NewLonLat(-140, 49.25)
+	nv := ll.ToNVector()
+	rot := nv.RotationMatrix()
+	if !rot.IsOrthonormal(1e-12) {
+		t.Fail()
+	}
+
+	// perturb the matrix as accumulated rounding might
+	drifted := rot
+	drifted[0][1] += 1e-4
+	drifted[2][0] -= 2e-4
+	drifted[1][1] *= 1.0003
+	if drifted.IsOrthonormal(1e-6) {
+		t.Fail()
+	}
+	fixed := drifted.Orthonormalize()
+	if !fixed.IsOrthonormal(1e-12) {
+		t.Error(fixed)
+	}
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			if !isclose(fixed[i][j], rot[i][j], 3) {
+				t.Errorf("element %d,%d: %f != %f", i, j, fixed[i][j], rot[i][j])
+			}
+		}
+	}
+
+	// a rotation is unchanged
+	same := rot.Orthonormalize()
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			if !isclose(same[i][j], rot[i][j], 12) {
+				t.Fail()
+			}
+		}
+	}
+
+	var singular Matrix3
+	if singular.Orthonormalize() != (Matrix3{}) {
+		t.Fail()
+	}
+}
+
+func TestRotationAboutAxis(t *testing.T) {
+	rot := RotationAboutAxis(Vec3{0, 0, 2}, 0.5*math.Pi)
+	v := rot.Mult(&Vec3{1, 0, 0})
+	if !isclose(v[0], 0, 12) || !isclose(v[1], 1, 12) || !isclose(v[2], 0, 12) {
+		t.Fail()
+	}
+}
+
+func TestRotationBetween(t *testing.T) {
+	ll1, _ := NewLonLat(-30, 50)
+	ll2, _ := NewLonLat(100, -20)
+	nv1 := ll1.ToNVector()
+	nv2 := ll2.ToNVector()
+
+	rot := RotationBetween(&nv1, &nv2)
+	v := rot.Mult(&nv1.Vec3)
+	for i := 0; i < 3; i++ {
+		if !isclose(v[i], nv2.Vec3[i], 12) {
+			t.Fail()
+		}
+	}
+
+	// antipodal
+	anti := NVector{Vec3{-nv1.Vec3[0], -nv1.Vec3[1], -nv1.Vec3[2]}}
+	rot = RotationBetween(&nv1, &anti)
+	v = rot.Mult(&nv1.Vec3)
+	for i := 0; i < 3; i++ {
+		if !isclose(v[i], anti.Vec3[i], 12) {
+			t.Fail()
+		}
+	}
+}
+
+func TestAlignToNorth(t *testing.T) {
+	track := lonLatRing([][2]float64{{-123.1, 49.3}, {-122.0, 48.0}, {-121.0, 48.5}, {-120.5, 47.0}})
+	aligned, rot := AlignToNorth(track)
+
+	if !isclose(aligned[0].Vec3[0], 1, 12) {
+		t.Fail()
+	}
+	if !isclose(bearing(&aligned[0].Vec3, &aligned[1].Vec3), 0, 12) {
+		t.Fail()
+	}
+
+	// the rotation is orthonormal, and preserves distances along the track
+	rrt := rot.Transpose()
+	prod := rot.MultMatrix(&rrt)
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			expected := 0.0
+			if i == j {
+				expected = 1.0
+			}
+			if !isclose(prod[i][j], expected, 12) {
+				t.Fail()
+			}
+		}
+	}
+	for i := 1; i < len(track); i++ {
+		if !isclose(track[i-1].SphericalDistance(&track[i], 1.0),
+			aligned[i-1].SphericalDistance(&aligned[i], 1.0), 12) {
+			t.Fail()
+		}
+	}
+}
+
+func TestLocalFrame(t *testing.T) {
+	ll, _ := NewLonLat(30, 45)
+	nv := ll.ToNVector()
+	frame := nv.LocalFrame()
+
+	// down points to the centre of the Earth, opposite the n-vector
+	down := frame.ECEFtoLocal(Vec3{-nv.Vec3[0], -nv.Vec3[1], -nv.Vec3[2]})
+	if !isclose(down[0], 0, 12) || !isclose(down[1], 0, 12) || !isclose(down[2], 1, 12) {
+		t.Error(down)
+	}
+	// north at the origin has a positive z component
+	north := frame.LocalToECEF(Vec3{1, 0, 0})
+	if !(north[2] > 0) || !isclose(dot(&north, &nv.Vec3), 0, 12) {
+		t.Error(north)
+	}
+	// east is in the equatorial plane
+	east := frame.LocalToECEF(Vec3{0, 1, 0})
+	if !isclose(east[2], 0, 12) || !(east[1] > 0) {
+		t.Error(east)
+	}
+
+	v := Vec3{3, -4, 12}
+	back := frame.ECEFtoLocal(frame.LocalToECEF(v))
+	for i := range v {
+		if !isclose(back[i], v[i], 12) {
+			t.Error(back)
+		}
+	}
+}
+
+func TestRotationQuaternion(t *testing.T) {
+	for _, c := range [][2]float64{{0, 0}, {-140, 49.25}, {100, -80}, {179, 5}, {-90, 1}} {
+		ll, _ := NewLonLat(c[0], c[1])
+		nv := ll.ToNVector()
+		q := nv.RotationQuaternion()
+		if !isclose(q[0]*q[0]+q[1]*q[1]+q[2]*q[2]+q[3]*q[3], 1, 12) {
+			t.Fail()
+		}
+		m := nv.RotationMatrix()
+		m2 := QuaternionToMatrix(q)
+		for i := 0; i < 3; i++ {
+			for j := 0; j < 3; j++ {
+				if !isclose(m[i][j], m2[i][j], 12) {
+					t.Errorf("%v: element %d,%d: %f != %f", c, i, j, m[i][j], m2[i][j])
+				}
+			}
+		}
+	}
+}
+
+func TestQuaternionToMatrix(t *testing.T) {
+	// a quarter turn about z
+	q := [4]float64{math.Cos(math.Pi / 4), 0, 0, math.Sin(math.Pi / 4)}
+	m := QuaternionToMatrix(q)
+	v := m.Mult(&Vec3{1, 0, 0})
+	if !isclose(v[0], 0, 12) || !isclose(v[1], 1, 12) || !isclose(v[2], 0, 12) {
+		t.Fail()
+	}
+}
+
+func eulerMatrix(roll, pitch, yaw float64) Matrix3 {
+	rz := RotationAboutAxis(Vec3{0, 0, 1}, yaw)
+	ry := RotationAboutAxis(Vec3{0, 1, 0}, pitch)
+	rx := RotationAboutAxis(Vec3{1, 0, 0}, roll)
+	ryx := ry.MultMatrix(&rx)
+	return rz.MultMatrix(&ryx)
+}
+
+func TestEulerAngles(t *testing.T) {
+	for _, c := range [][3]float64{{0.1, 0.2, 0.3}, {-2.5, -1.2, 3.0}, {3.1, 0.7, -1.9}, {0, 0, 0}} {
+		m := eulerMatrix(c[0], c[1], c[2])
+		roll, pitch, yaw := m.EulerAngles()
+		if !isclose(roll, c[0], 10) || !isclose(pitch, c[1], 10) || !isclose(yaw, c[2], 10) {
+			t.Error(c, roll, pitch, yaw)
+		}
+	}
+}
+
+func TestEulerAnglesGimbalLock(t *testing.T) {
+	for _, pitch := range []float64{0.5 * math.Pi, -0.5 * math.Pi} {
+		m := eulerMatrix(0.4, pitch, 1.1)
+		roll, pitch2, yaw := m.EulerAngles()
+		if roll != 0 || !isclose(pitch2, pitch, 10) {
+			t.Fail()
+		}
+		// the angles found must reproduce the matrix
+		m2 := eulerMatrix(roll, pitch2, yaw)
+		for i := 0; i < 3; i++ {
+			for j := 0; j < 3; j++ {
+				if !isclose(m[i][j], m2[i][j], 10) {
+					t.Fail()
+				}
+			}
+		}
+	}
+}
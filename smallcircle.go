@@ -0,0 +1,136 @@
+package nvector
+
+import "math"
+
+// SmallCircle is the boundary of a spherical cap: the positions at angular
+// distance Radius (in radians) from Center. To describe a range ring of
+// distance d on a sphere with radius R, use a Radius of d/R.
+type SmallCircle struct {
+	Center NVector
+	Radius float64
+}
+
+// contains returns whether *nv* lies within the cap bounded by the circle,
+// boundary included
+func (sc *SmallCircle) contains(nv *NVector) bool {
+	return sc.Center.AngleTo(nv) <= sc.Radius
+}
+
+// GreatSmallIntersection returns the two points where the great circle through
+// *a* and *b* crosses the small circle *sc*. Travelling along the great circle
+// from *a* towards *b*, the first point returned is where the great circle
+// enters the cap and the second where it leaves. NoIntersectionError is
+// returned if the great circle misses or only touches the small circle, and
+// ParallelGreatCirclesError if *a* and *b* don't define a great circle.
+func GreatSmallIntersection(a, b *NVector, sc SmallCircle) (NVector, NVector, error) {
+	n := a.GreatCircleNormal(b)
+	if n.Magnitude() == 0 {
+		return NVector{}, NVector{}, ParallelGreatCirclesError{}
+	}
+
+	// the point on the great circle nearest the centre of the cap, at angular
+	// distance acos(d) from it
+	c := normalize(&sc.Center.Vec3)
+	cn := dot(&c, &n)
+	near := Vec3{c[0] - cn*n[0], c[1] - cn*n[1], c[2] - cn*n[2]}
+	d := near.Magnitude()
+	cosr := math.Cos(sc.Radius)
+	if d < 1e-12 || math.Abs(cosr) >= d {
+		return NVector{}, NVector{}, NoIntersectionError{}
+	}
+	near = Vec3{near[0] / d, near[1] / d, near[2] / d}
+
+	// the crossings are symmetric about *near*, offset along the direction of
+	// travel
+	along := cross(&n, &near)
+	alpha := cosr / d
+	beta := math.Sqrt(1 - alpha*alpha)
+	entry := Vec3{alpha*near[0] - beta*along[0],
+		alpha*near[1] - beta*along[1],
+		alpha*near[2] - beta*along[2]}
+	exit := Vec3{alpha*near[0] + beta*along[0],
+		alpha*near[1] + beta*along[1],
+		alpha*near[2] + beta*along[2]}
+	return NVector{normalize(&entry)}, NVector{normalize(&exit)}, nil
+}
+
+// ClipSegmentToCap returns the portion of the great circle segment from *a* to
+// *b* that lies inside the cap bounded by *cap*, as the start and end points of
+// the clipped arc in the direction of travel, and false if the segment doesn't
+// enter the cap. Segments that start or end inside the cap are clipped at the
+// endpoint. A cap smaller than a hemisphere always gives a single arc, but the
+// interior of a larger cap can split a segment in two, in which case the
+// slice holds the start and end of each arc in turn.
+func ClipSegmentToCap(a, b *NVector, cap SmallCircle) ([]NVector, bool) {
+	total := a.AngleTo(b)
+	entry, exit, err := GreatSmallIntersection(a, b, cap)
+	if err != nil {
+		// the great circle lies either wholly inside or wholly outside the cap
+		if cap.contains(a) && cap.contains(b) {
+			return []NVector{*a, *b}, true
+		}
+		return nil, false
+	}
+
+	// measure positions as angles along the great circle from *a*
+	n := a.GreatCircleNormal(b)
+	t := cross(&n, &a.Vec3)
+	angle := func(p *Vec3) float64 {
+		s := math.Atan2(dot(p, &t), dot(p, &a.Vec3))
+		if s < 0 {
+			s += 2 * math.Pi
+		}
+		return s
+	}
+	at := func(s float64) NVector {
+		switch s {
+		case 0:
+			return *a
+		case total:
+			return *b
+		}
+		return NVector{slerp(&a.Vec3, &b.Vec3, s/total)}
+	}
+
+	sEntry := angle(&entry.Vec3)
+	sExit := angle(&exit.Vec3)
+
+	var clipped []NVector
+	if sExit < sEntry {
+		// *a* is inside the cap
+		clipped = append(clipped, *a, at(math.Min(sExit, total)))
+	}
+	if sEntry <= total {
+		end := total
+		if sExit > sEntry {
+			end = math.Min(sExit, total)
+		}
+		clipped = append(clipped, at(sEntry), at(end))
+	}
+	return clipped, len(clipped) != 0
+}
+
+// CircumcircleCenter returns the centre of the small circle passing through
+// *a*, *b*, and *c*, and its angular radius in radians. The circle lies in
+// the plane through the three points, so its centre is the normal to that
+// plane, taken on the same side as the points. DegenerateGeometryError is
+// returned if the points coincide or lie on a common great circle, where no
+// unique centre exists.
+func CircumcircleCenter(a, b, c *NVector) (NVector, float64, error) {
+	ab := Vec3{b.Vec3[0] - a.Vec3[0], b.Vec3[1] - a.Vec3[1], b.Vec3[2] - a.Vec3[2]}
+	ac := Vec3{c.Vec3[0] - a.Vec3[0], c.Vec3[1] - a.Vec3[1], c.Vec3[2] - a.Vec3[2]}
+	axb := cross(&ab, &ac)
+	n := normalize(&axb)
+	if n.Magnitude() == 0 {
+		return NVector{}, 0, DegenerateGeometryError{"points are coincident or collinear"}
+	}
+	offset := dot(&n, &a.Vec3)
+	if math.Abs(offset) < 1e-12 {
+		return NVector{}, 0, DegenerateGeometryError{"points lie on a great circle"}
+	}
+	if offset < 0 {
+		n = Vec3{-n[0], -n[1], -n[2]}
+	}
+	center := NVector{n}
+	return center, center.AngleTo(a), nil
+}
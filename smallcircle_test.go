@@ -0,0 +1,119 @@
+package nvector
+
+import (
+	"math"
+	"testing"
+)
+
+func TestGreatSmallIntersection(t *testing.T) {
+	a, _ := NewLonLat(-10, 0)
+	b, _ := NewLonLat(10, 0)
+	center, _ := NewLonLat(0, 0)
+	cap := SmallCircle{center.ToNVector(), 2.0 * math.Pi / 180}
+
+	nva, nvb := a.ToNVector(), b.ToNVector()
+	entry, exit, err := GreatSmallIntersection(&nva, &nvb, cap)
+	if err != nil {
+		t.Error(err)
+	}
+	llEntry, llExit := entry.ToLonLat(), exit.ToLonLat()
+	if !isclose(llEntry.Lon*180/math.Pi, -2, 9) || !isclose(llEntry.Lat, 0, 12) {
+		t.Fail()
+	}
+	if !isclose(llExit.Lon*180/math.Pi, 2, 9) || !isclose(llExit.Lat, 0, 12) {
+		t.Fail()
+	}
+
+	// a parallel great circle too far from the cap to touch it
+	c, _ := NewLonLat(-10, 5)
+	d, _ := NewLonLat(10, 5)
+	nvc, nvd := c.ToNVector(), d.ToNVector()
+	if _, _, err := GreatSmallIntersection(&nvc, &nvd, cap); err == nil {
+		t.Fail()
+	}
+}
+
+func TestClipSegmentToCap(t *testing.T) {
+	center, _ := NewLonLat(20, 40)
+	nvCenter := center.ToNVector()
+	R := 6371e3
+	cap := SmallCircle{nvCenter, 100e3 / R}
+
+	// a segment passing through the cap, offset from its centre
+	a := nvCenter.Forward(0.3, 500e3, R)
+	b := nvCenter.Forward(0.3+3.0, 500e3, R)
+	clipped, ok := ClipSegmentToCap(&a, &b, cap)
+	if !ok || len(clipped) != 2 {
+		t.Fatal("expected a single interior arc")
+	}
+	for _, p := range clipped {
+		if !isclose(nvCenter.SphericalDistance(&p, R), 100e3, 4) {
+			t.Errorf("clipped endpoint at %f m from centre", nvCenter.SphericalDistance(&p, R))
+		}
+	}
+	if a.AngleTo(&clipped[0]) >= a.AngleTo(&clipped[1]) {
+		t.Error("clipped arc should run in the direction of the segment")
+	}
+	mid := clipped[0].Interpolate(&clipped[1], 0.5)
+	if nvCenter.SphericalDistance(&mid, R) >= 100e3 {
+		t.Fail()
+	}
+
+	// a segment starting inside the cap is clipped at its start
+	inner := nvCenter.Forward(1.0, 50e3, R)
+	clipped, ok = ClipSegmentToCap(&inner, &b, cap)
+	if !ok || len(clipped) != 2 || clipped[0] != inner {
+		t.Fail()
+	}
+
+	// a segment wholly inside the cap is returned unchanged
+	inner2 := nvCenter.Forward(2.0, 50e3, R)
+	clipped, ok = ClipSegmentToCap(&inner, &inner2, cap)
+	if !ok || len(clipped) != 2 || clipped[0] != inner || clipped[1] != inner2 {
+		t.Fail()
+	}
+
+	// a segment that stops short of the cap
+	c := nvCenter.Forward(0.3+3.0, 200e3, R)
+	if _, ok := ClipSegmentToCap(&b, &c, cap); ok {
+		t.Fail()
+	}
+}
+
+func TestCircumcircleCenter(t *testing.T) {
+	R := 6371e3
+	pos, _ := NewLonLat(-60, -30)
+	nv := pos.ToNVector()
+	a := nv.Forward(0.2, 25e3, R)
+	b := nv.Forward(2.0, 25e3, R)
+	c := nv.Forward(4.5, 25e3, R)
+
+	center, radius, err := CircumcircleCenter(&a, &b, &c)
+	if err != nil {
+		t.Error(err)
+	}
+	if !isclose(center.SphericalDistance(&nv, R), 0, 4) {
+		t.Fail()
+	}
+	if !isclose(radius*R, 25e3, 4) {
+		t.Fail()
+	}
+	// ordering of the points doesn't matter
+	center2, _, _ := CircumcircleCenter(&c, &b, &a)
+	if !isclose(center2.SphericalDistance(&center, R), 0, 4) {
+		t.Fail()
+	}
+}
+
+func TestCircumcircleCenterDegenerate(t *testing.T) {
+	a, _ := NewLonLat(0, 0)
+	b, _ := NewLonLat(10, 0)
+	c, _ := NewLonLat(25, 0)
+	nva, nvb, nvc := a.ToNVector(), b.ToNVector(), c.ToNVector()
+	if _, _, err := CircumcircleCenter(&nva, &nvb, &nvc); err == nil {
+		t.Fail()
+	}
+	if _, _, err := CircumcircleCenter(&nva, &nva, &nvc); err == nil {
+		t.Fail()
+	}
+}
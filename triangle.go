@@ -0,0 +1,111 @@
+package nvector
+
+import (
+	"fmt"
+	"math"
+)
+
+// UnsolvableTriangleError is returned when the known parts of a spherical
+// triangle don't determine a triangle
+type UnsolvableTriangleError struct {
+	Reason string
+}
+
+func (e UnsolvableTriangleError) Error() string {
+	return fmt.Sprintf("cannot solve triangle: %s", e.Reason)
+}
+
+// SphericalTriangle holds the angles A, B, and C of a spherical triangle and
+// the lengths of the sides opposite them, SideA, SideB, and SideC, as angles
+// at the centre of the sphere. All are in radians.
+type SphericalTriangle struct {
+	A, B, C             float64
+	SideA, SideB, SideC float64
+}
+
+// hav returns the haversine of *x*
+func hav(x float64) float64 {
+	s := math.Sin(0.5 * x)
+	return s * s
+}
+
+// SphericalLawOfSines returns sin(a)/sin(A) for a side *a* and its opposite
+// angle *A*, in radians. By the spherical law of sines this ratio is the same
+// for all three side-angle pairs of a triangle.
+func SphericalLawOfSines(a, A float64) float64 {
+	return math.Sin(a) / math.Sin(A)
+}
+
+// LawOfSinesSolutions returns the parts of a triangle opposite a known side
+// or angle *x*, given the *ratio* from SphericalLawOfSines: the values y in
+// (0, pi) with sin(x)/sin(y) equal to *ratio*. For a side, *ratio* is used as
+// is to find its opposite angle; for an angle, its reciprocal gives the
+// opposite side. Since sin(y) = sin(pi-y), the law of sines alone can't tell
+// y from pi-y, and this is the ambiguous case: two values are returned
+// (smaller first) unless they coincide at pi/2, and none if no triangle
+// exists. Other parts of the triangle must decide between them.
+func LawOfSinesSolutions(ratio, x float64) []float64 {
+	s := math.Sin(x) / ratio
+	if !(math.Abs(s) <= 1) || s <= 0 {
+		return nil
+	}
+	y := math.Asin(s)
+	if y == 0.5*math.Pi {
+		return []float64{y}
+	}
+	return []float64{y, math.Pi - y}
+}
+
+// solveSAS returns the side opposite the included angle between sides *b*
+// and *c*, followed by the angles opposite *b* and *c*. The side is found
+// from the haversine form of the law of cosines and the angles from Napier's
+// analogies, both of which remain well conditioned for small triangles.
+func solveSAS(b, c, A float64) (float64, float64, float64) {
+	h := hav(b-c) + math.Sin(b)*math.Sin(c)*hav(A)
+	a := 2 * math.Asin(math.Sqrt(math.Max(0, math.Min(1, h))))
+
+	t := math.Tan(0.5 * A)
+	sum := 2 * math.Atan2(math.Cos(0.5*(b-c)), math.Cos(0.5*(b+c))*t)
+	diff := 2 * math.Atan2(math.Sin(0.5*(b-c)), math.Sin(0.5*(b+c))*t)
+	return a, 0.5 * (sum + diff), 0.5 * (sum - diff)
+}
+
+// solveSSS returns the angle opposite side *a* given the three sides, from
+// the spherical law of cosines
+func solveSSS(a, b, c float64) float64 {
+	cosA := (math.Cos(a) - math.Cos(b)*math.Cos(c)) / (math.Sin(b) * math.Sin(c))
+	return math.Acos(math.Max(-1, math.Min(1, cosA)))
+}
+
+// SolveSphericalTriangle returns *tri* with its unknown parts, given as zero,
+// filled in. The three sides (SSS), or two sides and the angle between them
+// (SAS), must be known; any other known parts are recomputed.
+// UnsolvableTriangleError is returned if neither case applies, or if the
+// sides given don't form a triangle.
+func SolveSphericalTriangle(tri SphericalTriangle) (SphericalTriangle, error) {
+	a, b, c := tri.SideA, tri.SideB, tri.SideC
+	for _, side := range []float64{a, b, c} {
+		if side < 0 || side >= math.Pi {
+			return tri, UnsolvableTriangleError{"sides must be between 0 and pi"}
+		}
+	}
+
+	switch {
+	case a > 0 && b > 0 && c > 0:
+		if a > b+c || b > a+c || c > a+b || a+b+c > 2*math.Pi {
+			return tri, UnsolvableTriangleError{"sides violate the triangle inequality"}
+		}
+		tri.A = solveSSS(a, b, c)
+		tri.B = solveSSS(b, c, a)
+		tri.C = solveSSS(c, a, b)
+	case b > 0 && c > 0 && tri.A > 0:
+		tri.SideA, tri.B, tri.C = solveSAS(b, c, tri.A)
+	case c > 0 && a > 0 && tri.B > 0:
+		tri.SideB, tri.C, tri.A = solveSAS(c, a, tri.B)
+	case a > 0 && b > 0 && tri.C > 0:
+		tri.SideC, tri.A, tri.B = solveSAS(a, b, tri.C)
+	default:
+		return tri, UnsolvableTriangleError{"requires three sides, or two sides and the included angle"}
+	}
+	return tri, nil
+}
@@ -0,0 +1,119 @@
+package nvector
+
+import (
+	"math"
+	"testing"
+)
+
+// triangleFromPositions returns the sides and angles of the spherical
+// triangle with vertices *pa*, *pb*, and *pc*
+func triangleFromPositions(pa, pb, pc *NVector) SphericalTriangle {
+	angle := func(at, to1, to2 *NVector) float64 {
+		return math.Abs(wrapAngle(bearing(&at.Vec3, &to2.Vec3) - bearing(&at.Vec3, &to1.Vec3)))
+	}
+	return SphericalTriangle{
+		A:     angle(pa, pb, pc),
+		B:     angle(pb, pc, pa),
+		C:     angle(pc, pa, pb),
+		SideA: pb.AngleTo(pc),
+		SideB: pc.AngleTo(pa),
+		SideC: pa.AngleTo(pb),
+	}
+}
+
+func closeTriangles(t *testing.T, got, want SphericalTriangle) {
+	if !isclose(got.A, want.A, 10) || !isclose(got.B, want.B, 10) || !isclose(got.C, want.C, 10) ||
+		!isclose(got.SideA, want.SideA, 10) || !isclose(got.SideB, want.SideB, 10) || !isclose(got.SideC, want.SideC, 10) {
+		t.Errorf("%+v != %+v", got, want)
+	}
+}
+
+func TestSphericalLawOfSines(t *testing.T) {
+	pts := lonLatRing([][2]float64{{0, 0}, {40, 10}, {15, 50}})
+	tri := triangleFromPositions(&pts[0], &pts[1], &pts[2])
+	ratio := SphericalLawOfSines(tri.SideA, tri.A)
+	if !isclose(ratio, SphericalLawOfSines(tri.SideB, tri.B), 10) ||
+		!isclose(ratio, SphericalLawOfSines(tri.SideC, tri.C), 10) {
+		t.Fail()
+	}
+
+	// from side b, the angle B is one of two candidates
+	candidates := LawOfSinesSolutions(ratio, tri.SideB)
+	if len(candidates) != 2 || !(candidates[0] < candidates[1]) {
+		t.Fatal(candidates)
+	}
+	if !isclose(candidates[0], tri.B, 10) && !isclose(candidates[1], tri.B, 10) {
+		t.Error(candidates, tri.B)
+	}
+	if !isclose(candidates[0]+candidates[1], math.Pi, 12) {
+		t.Fail()
+	}
+	// and from angle C, the side c
+	sides := LawOfSinesSolutions(1/ratio, tri.C)
+	if !isclose(sides[0], tri.SideC, 10) && !isclose(sides[1], tri.SideC, 10) {
+		t.Error(sides, tri.SideC)
+	}
+}
+
+func TestLawOfSinesSolutionsSingleAndNone(t *testing.T) {
+	// a quadrantal angle has a single solution
+	if y := LawOfSinesSolutions(1, 0.5*math.Pi); len(y) != 1 || y[0] != 0.5*math.Pi {
+		t.Error(y)
+	}
+	// no angle has a sine greater than one
+	if y := LawOfSinesSolutions(0.5, 1.2); len(y) != 0 {
+		t.Error(y)
+	}
+}
+
+func TestSolveSphericalTriangleSSS(t *testing.T) {
+	// the octant triangle has right angles at every vertex
+	tri, err := SolveSphericalTriangle(SphericalTriangle{SideA: 0.5 * math.Pi, SideB: 0.5 * math.Pi, SideC: 0.5 * math.Pi})
+	if err != nil {
+		t.Error(err)
+	}
+	closeTriangles(t, tri, SphericalTriangle{0.5 * math.Pi, 0.5 * math.Pi, 0.5 * math.Pi, 0.5 * math.Pi, 0.5 * math.Pi, 0.5 * math.Pi})
+
+	llA, _ := NewLonLat(-30, 10)
+	llB, _ := NewLonLat(20, 45)
+	llC, _ := NewLonLat(5, -20)
+	pa, pb, pc := llA.ToNVector(), llB.ToNVector(), llC.ToNVector()
+	want := triangleFromPositions(&pa, &pb, &pc)
+	tri, err = SolveSphericalTriangle(SphericalTriangle{SideA: want.SideA, SideB: want.SideB, SideC: want.SideC})
+	if err != nil {
+		t.Error(err)
+	}
+	closeTriangles(t, tri, want)
+}
+
+func TestSolveSphericalTriangleSAS(t *testing.T) {
+	llA, _ := NewLonLat(-30, 10)
+	llB, _ := NewLonLat(20, 45)
+	llC, _ := NewLonLat(5, -20)
+	pa, pb, pc := llA.ToNVector(), llB.ToNVector(), llC.ToNVector()
+	want := triangleFromPositions(&pa, &pb, &pc)
+
+	for _, given := range []SphericalTriangle{
+		{A: want.A, SideB: want.SideB, SideC: want.SideC},
+		{B: want.B, SideC: want.SideC, SideA: want.SideA},
+		{C: want.C, SideA: want.SideA, SideB: want.SideB},
+	} {
+		tri, err := SolveSphericalTriangle(given)
+		if err != nil {
+			t.Error(err)
+		}
+		closeTriangles(t, tri, want)
+	}
+}
+
+func TestSolveSphericalTriangleInvalid(t *testing.T) {
+	for _, given := range []SphericalTriangle{
+		{SideA: 1, SideB: 0.2, SideC: 0.3},
+		{A: 1, SideA: 1, SideB: 1},
+		{SideA: 4, SideB: 1, SideC: 1},
+	} {
+		if _, err := SolveSphericalTriangle(given); err == nil {
+			t.Errorf("expected error for %+v", given)
+		}
+	}
+}
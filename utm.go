@@ -0,0 +1,162 @@
+package nvector
+
+import (
+	"fmt"
+	"math"
+)
+
+// InvalidZoneError is returned for a UTM zone number outside 1 to 60
+type InvalidZoneError struct {
+	Zone int
+}
+
+func (e InvalidZoneError) Error() string {
+	return fmt.Sprintf("invalid UTM zone: %d", e.Zone)
+}
+
+const (
+	utmScale         = 0.9996
+	utmFalseEasting  = 500000.0
+	utmFalseNorthing = 10000000.0
+	utmMinLat        = -80.0
+	utmMaxLat        = 84.0
+)
+
+// utmZone returns the UTM zone containing a longitude and latitude in
+// degrees, including the exceptions for southwest Norway and Svalbard
+func utmZone(londeg, latdeg float64) int {
+	if latdeg >= 56 && latdeg < 64 && londeg >= 3 && londeg < 12 {
+		return 32
+	}
+	if latdeg >= 72 && londeg >= 0 && londeg < 42 {
+		switch {
+		case londeg < 9:
+			return 31
+		case londeg < 21:
+			return 33
+		case londeg < 33:
+			return 35
+		default:
+			return 37
+		}
+	}
+	zone := int(math.Floor((londeg+180)/6)) + 1
+	if zone > 60 {
+		zone = 1
+	}
+	return zone
+}
+
+// krugerCoefficients returns the rectifying radius and the coefficients of
+// Krüger's series for the transverse Mercator projection, to fourth order in
+// the third flattening
+func krugerCoefficients(ellps *Ellipsoid) (A float64, alpha, beta [4]float64) {
+	f := ellps.flattening()
+	n := f / (2 - f)
+	n2, n3, n4 := n*n, n*n*n, n*n*n*n
+	A = ellps.a / (1 + n) * (1 + n2/4 + n4/64)
+	alpha = [4]float64{
+		n/2 - 2*n2/3 + 5*n3/16 + 41*n4/180,
+		13*n2/48 - 3*n3/5 + 557*n4/1440,
+		61*n3/240 - 103*n4/140,
+		49561 * n4 / 161280,
+	}
+	beta = [4]float64{
+		n/2 - 2*n2/3 + 37*n3/96 - n4/360,
+		n2/48 + n3/15 - 437*n4/1440,
+		17*n3/480 - 37*n4/840,
+		4397 * n4 / 161280,
+	}
+	return A, alpha, beta
+}
+
+// gaussKruger returns the easting and northing in meters of the transverse
+// Mercator projection with unit central scale, for latitude *lat* and
+// longitude *dlon* from the central meridian
+func gaussKruger(lat, dlon float64, ellps *Ellipsoid) (float64, float64) {
+	A, alpha, _ := krugerCoefficients(ellps)
+	e := math.Sqrt(1 - ellps.b*ellps.b/(ellps.a*ellps.a))
+
+	// conformal latitude, then the Gauss-Krüger coordinates on the sphere
+	sinlat := math.Sin(lat)
+	t := math.Sinh(math.Atanh(sinlat) - e*math.Atanh(e*sinlat))
+	xi0 := math.Atan2(t, math.Cos(dlon))
+	eta0 := math.Atanh(math.Sin(dlon) / math.Sqrt(1+t*t))
+
+	xi, eta := xi0, eta0
+	for j, a := range alpha {
+		k := 2 * float64(j+1)
+		xi += a * math.Sin(k*xi0) * math.Cosh(k*eta0)
+		eta += a * math.Cos(k*xi0) * math.Sinh(k*eta0)
+	}
+
+	return A * eta, A * xi
+}
+
+// ToUTM returns the Universal Transverse Mercator coordinates of the position
+// on the ellipsoid, along with its zone and whether it lies in the northern
+// hemisphere. The zone is chosen from the longitude, observing the
+// exceptions for southwest Norway and Svalbard. InvalidLatitudeError is
+// returned outside the latitudes covered by UTM, 80⁰S to 84⁰N.
+func (ll *LonLat) ToUTM(ellps *Ellipsoid) (easting, northing float64, zone int, north bool, err error) {
+	latdeg := ll.Lat * 180.0 / math.Pi
+	if latdeg < utmMinLat || latdeg > utmMaxLat {
+		return 0, 0, 0, false, InvalidLatitudeError{latdeg}
+	}
+	lon := wrapAngle(ll.Lon)
+	zone = utmZone(lon*180.0/math.Pi, latdeg)
+	dlon := wrapAngle(lon - (float64(zone)*6-183)*math.Pi/180.0)
+
+	x, y := gaussKruger(ll.Lat, dlon, ellps)
+	easting = utmFalseEasting + utmScale*x
+	northing = utmScale * y
+	north = ll.Lat >= 0
+	if !north {
+		northing += utmFalseNorthing
+	}
+	return easting, northing, zone, north, nil
+}
+
+// FromUTM returns the position on the ellipsoid with the given Universal
+// Transverse Mercator coordinates. InvalidZoneError is returned for zones
+// outside 1 to 60.
+func FromUTM(easting, northing float64, zone int, north bool, ellps *Ellipsoid) (LonLat, error) {
+	if zone < 1 || zone > 60 {
+		return LonLat{}, InvalidZoneError{zone}
+	}
+	if !north {
+		northing -= utmFalseNorthing
+	}
+
+	A, _, beta := krugerCoefficients(ellps)
+	e2 := 1 - ellps.b*ellps.b/(ellps.a*ellps.a)
+	e := math.Sqrt(e2)
+
+	xi := northing / (utmScale * A)
+	eta := (easting - utmFalseEasting) / (utmScale * A)
+	xi0, eta0 := xi, eta
+	for j, b := range beta {
+		k := 2 * float64(j+1)
+		xi0 -= b * math.Sin(k*xi) * math.Cosh(k*eta)
+		eta0 -= b * math.Cos(k*xi) * math.Sinh(k*eta)
+	}
+
+	// tangent of the conformal latitude, converted to the geodetic latitude
+	// by Newton's method
+	dlon := math.Atan2(math.Sinh(eta0), math.Cos(xi0))
+	tauc := math.Sin(xi0) / math.Sqrt(math.Sinh(eta0)*math.Sinh(eta0)+math.Cos(xi0)*math.Cos(xi0))
+	tau := tauc
+	for i := 0; i < 10; i++ {
+		sigma := math.Sinh(e * math.Atanh(e*tau/math.Sqrt(1+tau*tau)))
+		taui := tau*math.Sqrt(1+sigma*sigma) - sigma*math.Sqrt(1+tau*tau)
+		dtau := (tauc - taui) / math.Sqrt(1+taui*taui) *
+			(1 + (1-e2)*tau*tau) / ((1 - e2) * math.Sqrt(1+tau*tau))
+		tau += dtau
+		if math.Abs(dtau) < 1e-14 {
+			break
+		}
+	}
+
+	lon0 := (float64(zone)*6 - 183) * math.Pi / 180.0
+	return LonLat{wrapAngle(lon0 + dlon), math.Atan(tau)}, nil
+}
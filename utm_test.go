@@ -0,0 +1,93 @@
+package nvector
+
+import "testing"
+
+func TestToUTM(t *testing.T) {
+	wgs84 := Ellipsoid{6378137.0, 6356752.3142}
+
+	// on the central meridian, the northing is the scaled meridian arc
+	ll, _ := NewLonLat(3, 45)
+	easting, northing, zone, north, err := ll.ToUTM(&wgs84)
+	if err != nil {
+		t.Error(err)
+	}
+	if zone != 31 || !north {
+		t.Fail()
+	}
+	if !isclose(easting, 500000, 6) || !isclose(northing, 0.9996*4984944.378, 2) {
+		t.Fail()
+	}
+
+	// reference value from GeographicLib's GeoConvert: 38N 444140.54 3684706.36
+	ll, _ = NewLonLat(44.4, 33.3)
+	easting, northing, zone, north, err = ll.ToUTM(&wgs84)
+	if err != nil {
+		t.Error(err)
+	}
+	if zone != 38 || !north || !isclose(easting, 444140.54, 2) || !isclose(northing, 3684706.36, 2) {
+		t.Error(easting, northing, zone)
+	}
+
+	// southern hemisphere northings are offset
+	ll, _ = NewLonLat(3, -45)
+	_, northing, _, north, _ = ll.ToUTM(&wgs84)
+	if north || !isclose(northing, 10000000-0.9996*4984944.378, 2) {
+		t.Fail()
+	}
+}
+
+func TestUTMZoneExceptions(t *testing.T) {
+	wgs84 := Ellipsoid{6378137.0, 6356752.3142}
+	cases := []struct {
+		lon, lat float64
+		zone     int
+	}{
+		{5, 60, 32},   // Bergen
+		{2, 60, 31},   // west of the Norway exception
+		{15, 78, 33},  // Svalbard
+		{8, 78, 31},   // Svalbard
+		{25, 78, 35},  // Svalbard
+		{40, 78, 37},  // Svalbard
+		{180, 0, 1},   // antimeridian
+		{-177, 10, 1}, //
+	}
+	for _, c := range cases {
+		ll, _ := NewLonLat(c.lon, c.lat)
+		_, _, zone, _, err := ll.ToUTM(&wgs84)
+		if err != nil || zone != c.zone {
+			t.Errorf("%f, %f: expected zone %d, got %d", c.lon, c.lat, c.zone, zone)
+		}
+	}
+}
+
+func TestToUTMInvalidLatitude(t *testing.T) {
+	wgs84 := Ellipsoid{6378137.0, 6356752.3142}
+	for _, latdeg := range []float64{85, -81} {
+		ll, _ := NewLonLat(10, latdeg)
+		if _, _, _, _, err := ll.ToUTM(&wgs84); err == nil {
+			t.Errorf("expected error at latitude %f", latdeg)
+		}
+	}
+}
+
+func TestFromUTM(t *testing.T) {
+	wgs84 := Ellipsoid{6378137.0, 6356752.3142}
+	for _, c := range [][2]float64{{-79.3871, 43.6426}, {151.2, -33.9}, {5, 60}, {20, 83.5}, {-70, -79}} {
+		ll, _ := NewLonLat(c[0], c[1])
+		easting, northing, zone, north, err := ll.ToUTM(&wgs84)
+		if err != nil {
+			t.Error(err)
+		}
+		ll2, err := FromUTM(easting, northing, zone, north, &wgs84)
+		if err != nil {
+			t.Error(err)
+		}
+		if !isclose(ll2.Lon, ll.Lon, 10) || !isclose(ll2.Lat, ll.Lat, 10) {
+			t.Errorf("round trip failed for %v", c)
+		}
+	}
+
+	if _, err := FromUTM(500000, 0, 61, true, &wgs84); err == nil {
+		t.Fail()
+	}
+}